@@ -0,0 +1,213 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// AdjustOption is tone adjustment option. Zero values keep the image unchanged.
+type AdjustOption struct {
+	// Brightness in range (-100, 100), 0 is unchanged.
+	Brightness float64
+	// Contrast in range (-100, 100), 0 is unchanged.
+	Contrast float64
+	// Gamma is the gamma correction, 1.0 or 0 is unchanged.
+	Gamma float64
+	// Saturation in range (-100, 500), 0 is unchanged.
+	Saturation float64
+	// Hue shift in degrees (-180, 180), 0 is unchanged.
+	Hue float64
+}
+
+// Adjust applies the tone adjustments of the option to the image.
+func Adjust(base image.Image, option *AdjustOption) image.Image {
+	return option.do(base)
+}
+
+func (a *AdjustOption) do(base image.Image) image.Image {
+	if a.Brightness != 0 {
+		base = AdjustBrightness(base, a.Brightness)
+	}
+	if a.Contrast != 0 {
+		base = AdjustContrast(base, a.Contrast)
+	}
+	if a.Gamma != 0 && a.Gamma != 1 {
+		base = AdjustGamma(base, a.Gamma)
+	}
+	if a.Saturation != 0 {
+		base = AdjustSaturation(base, a.Saturation)
+	}
+	if a.Hue != 0 {
+		base = AdjustHue(base, a.Hue)
+	}
+	return base
+}
+
+// adjustLUT applies the lookup table to the color channels of the image.
+func adjustLUT(img image.Image, lut []uint8) *image.NRGBA {
+	src := newScanner(img)
+	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * dst.Stride
+			row := dst.Pix[i : i+src.w*4]
+			src.scan(0, y, src.w, y+1, row)
+			for j := 0; j < len(row); j += 4 {
+				row[j] = lut[row[j]]
+				row[j+1] = lut[row[j+1]]
+				row[j+2] = lut[row[j+2]]
+			}
+		}
+	})
+	return dst
+}
+
+// adjustFunc applies fn to each pixel of the image.
+func adjustFunc(img image.Image, fn func(c color.NRGBA) color.NRGBA) *image.NRGBA {
+	src := newScanner(img)
+	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * dst.Stride
+			row := dst.Pix[i : i+src.w*4]
+			src.scan(0, y, src.w, y+1, row)
+			for j := 0; j < len(row); j += 4 {
+				c := fn(color.NRGBA{row[j], row[j+1], row[j+2], row[j+3]})
+				row[j], row[j+1], row[j+2], row[j+3] = c.R, c.G, c.B, c.A
+			}
+		}
+	})
+	return dst
+}
+
+// AdjustBrightness changes the brightness of the image by percentage in range
+// (-100, 100). For example, 20 makes the image 20% brighter.
+func AdjustBrightness(img image.Image, percentage float64) image.Image {
+	percentage = min(max(percentage, -100), 100)
+	shift := 255 * percentage / 100
+	lut := make([]uint8, 256)
+	for i := range lut {
+		lut[i] = clamp(float64(i) + shift)
+	}
+	return adjustLUT(img, lut)
+}
+
+// AdjustContrast changes the contrast of the image by percentage in range
+// (-100, 100). For example, -20 decreases the contrast by 20%.
+func AdjustContrast(img image.Image, percentage float64) image.Image {
+	percentage = min(max(percentage, -100), 100)
+	v := (100 + percentage) / 100
+	lut := make([]uint8, 256)
+	for i := range lut {
+		switch {
+		case percentage <= -100:
+			lut[i] = 128
+		case percentage >= 100:
+			if i < 128 {
+				lut[i] = 0
+			} else {
+				lut[i] = 255
+			}
+		default:
+			lut[i] = clamp((float64(i)/255-0.5)*v*v*255 + 127.5)
+		}
+	}
+	return adjustLUT(img, lut)
+}
+
+// AdjustGamma applies gamma correction to the image. Values below 1.0 darken the
+// image and values above 1.0 lighten it.
+func AdjustGamma(img image.Image, gamma float64) image.Image {
+	if gamma <= 0 {
+		return clone(img)
+	}
+	lut := make([]uint8, 256)
+	for i := range lut {
+		lut[i] = clamp(math.Pow(float64(i)/255, 1/gamma) * 255)
+	}
+	return adjustLUT(img, lut)
+}
+
+// AdjustSaturation changes the saturation of the image by percentage in range
+// (-100, 500). For example, -100 produces a grayscale image and 50 makes the
+// colors 50% more saturated.
+func AdjustSaturation(img image.Image, percentage float64) image.Image {
+	multiplier := 1 + min(max(percentage, -100), 500)/100
+	return adjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		h, s, l := rgbToHSL(c.R, c.G, c.B)
+		r, g, b := hslToRGB(h, min(s*multiplier, 1), l)
+		return color.NRGBA{r, g, b, c.A}
+	})
+}
+
+// AdjustHue rotates the hue of the image by shift degrees in range (-180, 180).
+func AdjustHue(img image.Image, shift float64) image.Image {
+	if math.Mod(shift, 360) == 0 {
+		return clone(img)
+	}
+	shift = shift / 360
+	return adjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		h, s, l := rgbToHSL(c.R, c.G, c.B)
+		h += shift
+		h -= math.Floor(h)
+		r, g, b := hslToRGB(h, s, l)
+		return color.NRGBA{r, g, b, c.A}
+	})
+}
+
+// rgbToHSL converts a color to hue, saturation and lightness, all in [0, 1].
+func rgbToHSL(r, g, b uint8) (h, s, l float64) {
+	rr, gg, bb := float64(r)/255, float64(g)/255, float64(b)/255
+	maxC, minC := max(rr, gg, bb), min(rr, gg, bb)
+	l = (maxC + minC) / 2
+	if maxC == minC {
+		return 0, 0, l
+	}
+	d := maxC - minC
+	if l > 0.5 {
+		s = d / (2 - maxC - minC)
+	} else {
+		s = d / (maxC + minC)
+	}
+	switch maxC {
+	case rr:
+		h = (gg - bb) / d
+		if gg < bb {
+			h += 6
+		}
+	case gg:
+		h = (bb-rr)/d + 2
+	default:
+		h = (rr-gg)/d + 4
+	}
+	return h / 6, s, l
+}
+
+// hslToRGB converts hue, saturation and lightness in [0, 1] to a color.
+func hslToRGB(h, s, l float64) (r, g, b uint8) {
+	if s == 0 {
+		v := clamp(l * 255)
+		return v, v, v
+	}
+	var q float64
+	if l < 0.5 {
+		q = l * (1 + s)
+	} else {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	hue := func(t float64) float64 {
+		t -= math.Floor(t)
+		switch {
+		case t < 1.0/6:
+			return p + (q-p)*6*t
+		case t < 0.5:
+			return q
+		case t < 2.0/3:
+			return p + (q-p)*(2.0/3-t)*6
+		}
+		return p
+	}
+	return clamp(hue(h+1.0/3) * 255), clamp(hue(h) * 255), clamp(hue(h-1.0/3) * 255)
+}
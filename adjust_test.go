@@ -0,0 +1,52 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestAdjust(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{200, 100, 50, 128})
+
+	testCase := []struct {
+		name string
+		img  image.Image
+		want color.NRGBA
+	}{
+		{"brightness", AdjustBrightness(src, 20), color.NRGBA{251, 151, 101, 128}},
+		{"brightness", AdjustBrightness(src, -100), color.NRGBA{0, 0, 0, 128}},
+		{"contrast", AdjustContrast(src, -100), color.NRGBA{128, 128, 128, 128}},
+		{"contrast", AdjustContrast(src, 100), color.NRGBA{255, 0, 0, 128}},
+		{"gamma", AdjustGamma(src, 1), color.NRGBA{200, 100, 50, 128}},
+		{"gamma", AdjustGamma(src, 2), color.NRGBA{226, 160, 113, 128}},
+		{"saturation", AdjustSaturation(src, -100), color.NRGBA{125, 125, 125, 128}},
+		{"saturation", AdjustSaturation(src, 0), color.NRGBA{200, 100, 50, 128}},
+		{"hue", AdjustHue(src, 120), color.NRGBA{50, 200, 100, 128}},
+		{"hue", AdjustHue(src, -120), color.NRGBA{100, 50, 200, 128}},
+		{"hue", AdjustHue(src, 360), color.NRGBA{200, 100, 50, 128}},
+		{"adjust", Adjust(src, &AdjustOption{Hue: 120, Gamma: 1}), color.NRGBA{50, 200, 100, 128}},
+	}
+	for _, tc := range testCase {
+		if got := tc.img.At(0, 0); got != tc.want {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+
+	if c := AdjustContrast(src, 50).At(0, 0).(color.NRGBA); c.R <= 200 || c.B >= 50 {
+		t.Errorf("want more contrast, got %v", c)
+	}
+	if c := AdjustSaturation(src, 50).At(0, 0).(color.NRGBA); c.R <= 200 || c.B >= 50 {
+		t.Errorf("want more saturated color, got %v", c)
+	}
+}
+
+func TestHSL(t *testing.T) {
+	for _, c := range []color.NRGBA{{0, 0, 0, 0}, {255, 255, 255, 0}, {255, 0, 0, 0}, {12, 200, 99, 0}, {1, 2, 250, 0}, {128, 127, 126, 0}} {
+		h, s, l := rgbToHSL(c.R, c.G, c.B)
+		if r, g, b := hslToRGB(h, s, l); r != c.R || g != c.G || b != c.B {
+			t.Errorf("want %v, got %d, %d, %d", c, r, g, b)
+		}
+	}
+}
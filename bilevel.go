@@ -0,0 +1,388 @@
+package imgconv
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+)
+
+var (
+	_ encoding.TextUnmarshaler = new(ThresholdMethod)
+	_ encoding.TextMarshaler   = ThresholdMethod(0)
+)
+
+// bilevelPalette is the palette of the 1-bit images produced by thresholding.
+var bilevelPalette = color.Palette{color.Gray{0}, color.Gray{0xff}}
+
+// ThresholdMethod defines how the threshold level of each pixel is chosen.
+type ThresholdMethod int
+
+const (
+	// ThresholdFixed uses the same level for all pixels.
+	ThresholdFixed ThresholdMethod = iota
+	// ThresholdOtsu uses the global level which best separates the luminance
+	// histogram into two classes.
+	ThresholdOtsu
+	// ThresholdSauvola uses a local level based on the mean and standard deviation
+	// of a window around each pixel, suited to documents with uneven lighting.
+	ThresholdSauvola
+	// ThresholdNiblack uses a local level of the window mean plus k times its
+	// standard deviation.
+	ThresholdNiblack
+)
+
+var thresholdMethods = []string{
+	"fixed",
+	"otsu",
+	"sauvola",
+	"niblack",
+}
+
+func (m *ThresholdMethod) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	for index, method := range thresholdMethods {
+		if s == method {
+			*m = ThresholdMethod(index)
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported threshold method: %s", s)
+}
+
+func (m ThresholdMethod) MarshalText() ([]byte, error) {
+	if m < 0 || int(m) >= len(thresholdMethods) {
+		return []byte("unknown"), nil
+	}
+	return []byte(thresholdMethods[m]), nil
+}
+
+// ThresholdOption is threshold option
+type ThresholdOption struct {
+	Method ThresholdMethod
+	// Level is the threshold level of the ThresholdFixed method.
+	Level uint8
+	// Window is the size of the local window of the ThresholdSauvola and
+	// ThresholdNiblack methods, 25 if 0.
+	Window int
+	// K is the parameter of the ThresholdSauvola and ThresholdNiblack methods,
+	// 0.34 and -0.2 respectively if 0.
+	K float64
+}
+
+func (t *ThresholdOption) do(base image.Image) image.Image {
+	switch t.Method {
+	case ThresholdOtsu:
+		return OtsuThreshold(base)
+	case ThresholdSauvola:
+		return SauvolaThreshold(base, t.Window, t.K)
+	case ThresholdNiblack:
+		return NiblackThreshold(base, t.Window, t.K)
+	}
+	return Threshold(base, t.Level)
+}
+
+// isBilevel reports whether the image is a 1-bit image of opaque black and white
+// pixels. It returns the palette index of white.
+func isBilevel(img image.Image) (*image.Paletted, uint8, bool) {
+	p, ok := img.(*image.Paletted)
+	if !ok || len(p.Palette) != 2 {
+		return nil, 0, false
+	}
+	for _, c := range p.Palette {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			return nil, 0, false
+		}
+	}
+	c0 := color.GrayModel.Convert(p.Palette[0]).(color.Gray).Y
+	c1 := color.GrayModel.Convert(p.Palette[1]).(color.Gray).Y
+	switch {
+	case c0 == 0 && c1 == 0xff:
+		return p, 1, true
+	case c0 == 0xff && c1 == 0:
+		return p, 0, true
+	}
+	return nil, 0, false
+}
+
+// lumaPlane returns the luminance values of the image, row by row.
+func lumaPlane(img image.Image) ([]uint8, int, int) {
+	src := newScanner(img)
+	plane := make([]uint8, src.w*src.h)
+	parallel(0, src.h, func(ys <-chan int) {
+		row := make([]uint8, src.w*4)
+		for y := range ys {
+			src.scan(0, y, src.w, y+1, row)
+			for x := range src.w {
+				plane[y*src.w+x] = luma(row[x*4], row[x*4+1], row[x*4+2])
+			}
+		}
+	})
+	return plane, src.w, src.h
+}
+
+// binarize returns the 1-bit image of the pixels whose luminance is at least the
+// level returned by fn.
+func binarize(plane []uint8, w, h int, fn func(x, y int) float64) *image.Paletted {
+	dst := image.NewPaletted(image.Rect(0, 0, w, h), bilevelPalette)
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			for x := range w {
+				if float64(plane[y*w+x]) >= fn(x, y) {
+					dst.Pix[y*dst.Stride+x] = 1
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// Threshold converts the image to a 1-bit image, where pixels with a luminance of
+// at least level are white and the others are black.
+func Threshold(img image.Image, level uint8) *image.Paletted {
+	plane, w, h := lumaPlane(img)
+	return binarize(plane, w, h, func(_, _ int) float64 { return float64(level) })
+}
+
+// OtsuLevel returns the threshold level computed by Otsu's method, which
+// maximizes the variance between the dark and light classes of pixels.
+func OtsuLevel(img image.Image) uint8 {
+	plane, _, _ := lumaPlane(img)
+	return otsuLevel(plane)
+}
+
+func otsuLevel(plane []uint8) uint8 {
+	var hist [256]float64
+	for _, v := range plane {
+		hist[v]++
+	}
+	total := float64(len(plane))
+	var sum float64
+	for v, n := range hist {
+		sum += float64(v) * n
+	}
+	var best, sumB, weightB float64
+	var level int
+	for v, n := range hist {
+		if weightB += n; weightB == 0 {
+			continue
+		}
+		weightF := total - weightB
+		if weightF == 0 {
+			break
+		}
+		sumB += float64(v) * n
+		meanB, meanF := sumB/weightB, (sum-sumB)/weightF
+		if between := weightB * weightF * (meanB - meanF) * (meanB - meanF); between > best {
+			best, level = between, v
+		}
+	}
+	// Pixels above the last dark level are light.
+	return uint8(min(level+1, 255))
+}
+
+// OtsuThreshold converts the image to a 1-bit image using the level computed
+// by Otsu's method.
+func OtsuThreshold(img image.Image) *image.Paletted {
+	plane, w, h := lumaPlane(img)
+	level := float64(otsuLevel(plane))
+	return binarize(plane, w, h, func(_, _ int) float64 { return level })
+}
+
+// localStats returns a function computing the mean and standard deviation of the
+// luminance in the window centered on each pixel, using integral images.
+func localStats(plane []uint8, w, h, window int) func(x, y int) (mean, stddev float64) {
+	sum := make([]float64, (w+1)*(h+1))
+	sq := make([]float64, (w+1)*(h+1))
+	for y := range h {
+		var rowSum, rowSq float64
+		for x := range w {
+			v := float64(plane[y*w+x])
+			rowSum += v
+			rowSq += v * v
+			i := (y+1)*(w+1) + x + 1
+			sum[i] = sum[i-w-1] + rowSum
+			sq[i] = sq[i-w-1] + rowSq
+		}
+	}
+	r := window / 2
+	return func(x, y int) (float64, float64) {
+		x0, y0 := max(x-r, 0), max(y-r, 0)
+		x1, y1 := min(x+r+1, w), min(y+r+1, h)
+		n := float64((x1 - x0) * (y1 - y0))
+		a, b, c, d := y0*(w+1)+x0, y0*(w+1)+x1, y1*(w+1)+x0, y1*(w+1)+x1
+		mean := (sum[d] - sum[b] - sum[c] + sum[a]) / n
+		variance := (sq[d]-sq[b]-sq[c]+sq[a])/n - mean*mean
+		return mean, math.Sqrt(max(variance, 0))
+	}
+}
+
+// SauvolaThreshold converts the image to a 1-bit image using Sauvola's local
+// threshold mean * (1 + k * (stddev / 128 - 1)) over a window x window area.
+// Default values are used for a window of 0 (25) and a k of 0 (0.34).
+func SauvolaThreshold(img image.Image, window int, k float64) *image.Paletted {
+	if window <= 0 {
+		window = 25
+	}
+	if k == 0 {
+		k = 0.34
+	}
+	plane, w, h := lumaPlane(img)
+	stats := localStats(plane, w, h, window)
+	return binarize(plane, w, h, func(x, y int) float64 {
+		mean, stddev := stats(x, y)
+		return mean * (1 + k*(stddev/128-1))
+	})
+}
+
+// NiblackThreshold converts the image to a 1-bit image using Niblack's local
+// threshold mean + k * stddev over a window x window area.
+// Default values are used for a window of 0 (25) and a k of 0 (-0.2).
+func NiblackThreshold(img image.Image, window int, k float64) *image.Paletted {
+	if window <= 0 {
+		window = 25
+	}
+	if k == 0 {
+		k = -0.2
+	}
+	plane, w, h := lumaPlane(img)
+	stats := localStats(plane, w, h, window)
+	return binarize(plane, w, h, func(x, y int) float64 {
+		mean, stddev := stats(x, y)
+		return mean + k*stddev
+	})
+}
+
+// packBilevel returns the rows of the 1-bit image m with eight pixels per byte,
+// in which the white pixels are set.
+func packBilevel(m *image.Paletted, white uint8) []byte {
+	width, height := m.Rect.Dx(), m.Rect.Dy()
+	rowSize := (width + 7) / 8
+	data := make([]byte, rowSize*height)
+	for y := range height {
+		row := data[y*rowSize : (y+1)*rowSize]
+		for x, v := range m.Pix[y*m.Stride : y*m.Stride+width] {
+			if v == white {
+				row[x/8] |= 0x80 >> (x % 8)
+			}
+		}
+	}
+	return data
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeBilevelTIFF writes the 1-bit image m as a TIFF image with one bit per
+// pixel, which the generic TIFF encoder stores as an 8-bit palette image.
+func encodeBilevelTIFF(w io.Writer, m *image.Paletted, white uint8, compression TIFFCompression) error {
+	width, height := m.Rect.Dx(), m.Rect.Dy()
+	// BlackIsZero, so white pixels are set.
+	data := packBilevel(m, white)
+	comp := uint16(1)
+	if compression == TIFFDeflate {
+		var err error
+		if data, err = deflate(data); err != nil {
+			return err
+		}
+		comp = 8
+	}
+
+	const (
+		typeShort    = 3
+		typeLong     = 4
+		typeRational = 5
+	)
+	le := binary.LittleEndian
+	b := []byte("II*\x00")
+	b = le.AppendUint32(b, uint32(8+len(data)+len(data)%2))
+	b = append(b, data...)
+	if len(data)%2 != 0 {
+		b = append(b, 0) // The IFD starts on a word boundary.
+	}
+	entries := []struct {
+		tag, typ uint16
+		value    uint32
+	}{
+		{tagImageWidth, typeLong, uint32(width)},
+		{tagImageLength, typeLong, uint32(height)},
+		{258, typeShort, 1},                // BitsPerSample
+		{259, typeShort, uint32(comp)},     // Compression
+		{262, typeShort, 1},                // PhotometricInterpretation: BlackIsZero
+		{273, typeLong, 8},                 // StripOffsets
+		{278, typeLong, uint32(height)},    // RowsPerStrip
+		{279, typeLong, uint32(len(data))}, // StripByteCounts
+		{tagXResolution, typeRational, 0},  // offset set below
+		{tagYResolution, typeRational, 0},  // offset set below
+		{tagResolutionUnit, typeShort, 2},  // inch
+	}
+	resOffset := uint32(len(b) + 2 + len(entries)*12 + 4)
+	b = le.AppendUint16(b, uint16(len(entries)))
+	for _, e := range entries {
+		b = le.AppendUint16(b, e.tag)
+		b = le.AppendUint16(b, e.typ)
+		b = le.AppendUint32(b, 1)
+		switch e.typ {
+		case typeShort:
+			b = le.AppendUint16(b, uint16(e.value))
+			b = le.AppendUint16(b, 0)
+		case typeRational:
+			b = le.AppendUint32(b, resOffset)
+		default:
+			b = le.AppendUint32(b, e.value)
+		}
+	}
+	b = le.AppendUint32(b, 0) // no next IFD
+	// 72 dots per inch, shared by both resolutions.
+	b = le.AppendUint32(le.AppendUint32(b, 72), 1)
+	_, err := w.Write(b)
+	return err
+}
+
+// encodeBilevelPDF writes the 1-bit image m as a single page PDF document holding
+// an image with one bit per pixel, which the generic PDF encoder stores with 8 bits
+// per component. The page has the size of the image at 72 dots per inch.
+func encodeBilevelPDF(w io.Writer, m *image.Paletted, white uint8) error {
+	width, height := m.Rect.Dx(), m.Rect.Dy()
+	// DeviceGray, so white pixels are set.
+	data, err := deflate(packBilevel(m, white))
+	if err != nil {
+		return err
+	}
+	content := fmt.Sprintf("q %d 0 0 %d 0 0 cm /Im0 Do Q", width, height)
+	objects := []string{
+		"<</Type/Catalog/Pages 2 0 R>>",
+		"<</Type/Pages/Kids[3 0 R]/Count 1>>",
+		fmt.Sprintf("<</Type/Page/Parent 2 0 R/MediaBox[0 0 %d %d]/Resources<</XObject<</Im0 4 0 R>>>>/Contents 5 0 R>>", width, height),
+		fmt.Sprintf("<</Type/XObject/Subtype/Image/Width %d/Height %d/ColorSpace/DeviceGray/BitsPerComponent 1/Filter/FlateDecode/Length %d>>\nstream\n%s\nendstream", width, height, len(data), data),
+		fmt.Sprintf("<</Length %d>>\nstream\n%s\nendstream", len(content), content),
+	}
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<</Size %d/Root 1 0 R>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	_, err = w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,176 @@
+package imgconv
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// unevenPage returns a 64x64 page lit from the left, from gray 60 to 250, with
+// dark 2-pixel strokes every 8 pixels which are lighter on the right than the
+// paper on the left.
+func unevenPage() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for y := range 64 {
+		for x := range 64 {
+			paper := 60 + float64(x)*3
+			if y%8 < 2 {
+				img.Pix[y*img.Stride+x] = uint8(paper * 0.4)
+			} else {
+				img.Pix[y*img.Stride+x] = uint8(paper)
+			}
+		}
+	}
+	return img
+}
+
+func TestThreshold(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 4, 1))
+	src.Pix = []uint8{10, 127, 128, 250}
+	img := Threshold(src, 128)
+	if want := []uint8{0, 0, 1, 1}; !bytes.Equal(img.Pix, want) {
+		t.Errorf("want %v, got %v", want, img.Pix)
+	}
+	if _, white, ok := isBilevel(img); !ok || white != 1 {
+		t.Errorf("want bilevel image with white index 1, got %v, %d", ok, white)
+	}
+
+	// Two classes around 40 and 200.
+	src = image.NewGray(image.Rect(0, 0, 8, 1))
+	src.Pix = []uint8{35, 40, 45, 42, 195, 200, 205, 210}
+	if level := OtsuLevel(src); level <= 45 || level > 195 {
+		t.Errorf("want level between classes, got %d", level)
+	}
+	if img := OtsuThreshold(src); !bytes.Equal(img.Pix, []uint8{0, 0, 0, 0, 1, 1, 1, 1}) {
+		t.Errorf("want classes separated, got %v", img.Pix)
+	}
+
+	if _, _, ok := isBilevel(src); ok {
+		t.Error("want gray image not bilevel")
+	}
+	if _, _, ok := isBilevel(image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black, color.Gray{128}})); ok {
+		t.Error("want black and gray palette not bilevel")
+	}
+	transparent := image.NewPaletted(image.Rect(0, 0, 2, 1), color.Palette{color.Transparent, color.White})
+	transparent.Pix[1] = 1
+	if _, _, ok := isBilevel(transparent); ok {
+		t.Error("want transparent palette not bilevel")
+	}
+
+	var m ThresholdMethod
+	if err := m.UnmarshalText([]byte("Sauvola")); err != nil || m != ThresholdSauvola {
+		t.Errorf("want sauvola, got %v, %v", m, err)
+	}
+	if err := m.UnmarshalText([]byte("bernsen")); err == nil {
+		t.Error("want error for unsupported method")
+	}
+}
+
+func TestLocalThreshold(t *testing.T) {
+	page := unevenPage()
+	check := func(name string, img *image.Paletted) {
+		t.Helper()
+		var errors int
+		for y := range 64 {
+			for x := range 64 {
+				want := uint8(1)
+				if y%8 < 2 {
+					want = 0
+				}
+				if img.ColorIndexAt(x, y) != want {
+					errors++
+				}
+			}
+		}
+		if errors > 64*64/50 {
+			t.Errorf("%s: want strokes separated from paper, got %d errors", name, errors)
+		}
+	}
+	check("sauvola", SauvolaThreshold(page, 15, 0))
+	check("niblack", NiblackThreshold(page, 15, 0))
+
+	mean, stddev := localStats([]uint8{0, 10, 20, 30}, 2, 2, 3)(0, 0)
+	if mean != 15 || math.Abs(stddev-math.Sqrt(125)) > 1e-9 {
+		t.Errorf("want 15, %g, got %g, %g", math.Sqrt(125), mean, stddev)
+	}
+}
+
+func TestEncodeBilevel(t *testing.T) {
+	img := (&ThresholdOption{Method: ThresholdSauvola, Window: 15}).do(unevenPage()).(*image.Paletted)
+
+	for _, format := range []Format{PNG, TIFF} {
+		var buf bytes.Buffer
+		if err := (&FormatOption{format, []EncodeOption{DPI(300), BackgroundColor(color.White)}}).Encode(&buf, img); err != nil {
+			t.Fatal(format, err)
+		}
+		if md, err := ReadMetadata(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatal(format, err)
+		} else if md.DPI != 300 {
+			t.Errorf("%s: want 300 dpi, got %g", format, md.DPI)
+		}
+		decoded, err := Decode(&buf)
+		if err != nil {
+			t.Fatal(format, err)
+		}
+		for y := range 64 {
+			for x := range 64 {
+				want := img.Palette[img.ColorIndexAt(x, y)]
+				if got := color.GrayModel.Convert(decoded.At(x, y)); got != want {
+					t.Fatalf("%s: want %v at (%d, %d), got %v", format, want, x, y, got)
+				}
+			}
+		}
+	}
+
+	var bilevel, gray bytes.Buffer
+	if err := encodeBilevelTIFF(&bilevel, img, 1, TIFFUncompressed); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&FormatOption{TIFF, []EncodeOption{TIFFCompressionType(TIFFUncompressed)}}).Encode(&gray, ToGray(img)); err != nil {
+		t.Fatal(err)
+	}
+	if bilevel.Len()*4 > gray.Len() {
+		t.Errorf("want 1-bit tiff much smaller than %d bytes, got %d", gray.Len(), bilevel.Len())
+	}
+
+	// White index 0.
+	inverted := image.NewPaletted(image.Rect(0, 0, 9, 1), color.Palette{color.White, color.Black})
+	inverted.Pix[8] = 1
+	var buf bytes.Buffer
+	if err := (&FormatOption{Format: TIFF}).Encode(&buf, inverted); err != nil {
+		t.Fatal(err)
+	}
+	if decoded, err := Decode(&buf); err != nil {
+		t.Fatal(err)
+	} else if r, _, _, _ := decoded.At(0, 0).RGBA(); r != 0xffff {
+		t.Errorf("want white, got %v", decoded.At(0, 0))
+	} else if r, _, _, _ := decoded.At(8, 0).RGBA(); r != 0 {
+		t.Errorf("want black, got %v", decoded.At(8, 0))
+	}
+
+	// PDF images are stored losslessly with 1 bit per pixel.
+	buf.Reset()
+	if err := (&FormatOption{Format: PDF}).Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/BitsPerComponent 1")) {
+		t.Error("want 1-bit pdf image")
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Bounds().Size() != img.Bounds().Size() {
+		t.Fatalf("want size %v, got %v", img.Bounds().Size(), decoded.Bounds().Size())
+	}
+	for y := range 64 {
+		for x := range 64 {
+			want := img.Palette[img.ColorIndexAt(x, y)]
+			if got := color.GrayModel.Convert(decoded.At(x, y)); got != want {
+				t.Fatalf("pdf: want %v at (%d, %d), got %v", want, x, y, got)
+			}
+		}
+	}
+}
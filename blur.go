@@ -0,0 +1,126 @@
+package imgconv
+
+import (
+	"image"
+	"math"
+)
+
+// SharpenOption is unsharp mask option
+type SharpenOption struct {
+	// Radius is the standard deviation of the Gaussian blur, in pixels.
+	Radius float64
+	// Amount is the strength of the sharpening, 1.0 is 100%.
+	Amount float64
+	// Threshold is the minimum difference in levels (0-255) between a pixel and
+	// its blurred value to be sharpened, which avoids sharpening noise.
+	Threshold uint8
+}
+
+func (s *SharpenOption) do(base image.Image) image.Image {
+	return UnsharpMask(base, s.Radius, s.Amount, s.Threshold)
+}
+
+// gaussianKernel returns the normalized Gaussian kernel of the standard deviation
+// sigma, covering three standard deviations on each side.
+func gaussianKernel(sigma float64) []float64 {
+	radius := int(math.Ceil(sigma * 3))
+	kernel := make([]float64, radius*2+1)
+	var sum float64
+	for i := range kernel {
+		x := float64(i - radius)
+		kernel[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	return kernel
+}
+
+// GaussianBlur blurs the image using a Gaussian function with the standard
+// deviation sigma in pixels, which must be positive.
+func GaussianBlur(img image.Image, sigma float64) image.Image {
+	if sigma <= 0 {
+		return clone(img)
+	}
+	kernel := gaussianKernel(sigma)
+	return convolve1D(convolve1D(toNRGBA(img), kernel, false), kernel, true)
+}
+
+// convolve1D applies the kernel horizontally or vertically to the image, weighting
+// the color channels by alpha. Pixels outside the image repeat the edge pixels.
+func convolve1D(src *image.NRGBA, kernel []float64, vertical bool) *image.NRGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	radius := len(kernel) / 2
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			for x := range w {
+				var r, g, b, a float64
+				for k, weight := range kernel {
+					sx, sy := x, y
+					if vertical {
+						sy = min(max(y+k-radius, 0), h-1)
+					} else {
+						sx = min(max(x+k-radius, 0), w-1)
+					}
+					i := sy*src.Stride + sx*4
+					s := src.Pix[i : i+4 : i+4]
+					wa := float64(s[3]) * weight
+					r += float64(s[0]) * wa
+					g += float64(s[1]) * wa
+					b += float64(s[2]) * wa
+					a += wa
+				}
+				if a != 0 {
+					aInv := 1 / a
+					j := y*dst.Stride + x*4
+					d := dst.Pix[j : j+4 : j+4]
+					d[0] = clamp(r * aInv)
+					d[1] = clamp(g * aInv)
+					d[2] = clamp(b * aInv)
+					d[3] = clamp(a)
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// UnsharpMask sharpens the image by adding amount times the difference between
+// the image and its Gaussian blur of the standard deviation radius. Differences
+// below threshold levels are ignored.
+func UnsharpMask(img image.Image, radius, amount float64, threshold uint8) image.Image {
+	src := toNRGBA(img)
+	if radius <= 0 || amount <= 0 {
+		return clone(src)
+	}
+	blurred := GaussianBlur(src, radius).(*image.NRGBA)
+	dst := image.NewNRGBA(src.Rect)
+	parallel(0, src.Rect.Dy(), func(ys <-chan int) {
+		for y := range ys {
+			s := src.Pix[y*src.Stride : y*src.Stride+src.Rect.Dx()*4]
+			bl := blurred.Pix[y*blurred.Stride : y*blurred.Stride+src.Rect.Dx()*4]
+			d := dst.Pix[y*dst.Stride : y*dst.Stride+src.Rect.Dx()*4]
+			for i := range s {
+				if i%4 == 3 {
+					d[i] = s[i]
+					continue
+				}
+				diff := float64(s[i]) - float64(bl[i])
+				if math.Abs(diff) < float64(threshold) {
+					d[i] = s[i]
+					continue
+				}
+				d[i] = clamp(float64(s[i]) + amount*diff)
+			}
+		}
+	})
+	return dst
+}
+
+// Sharpen sharpens the image with an unsharp mask of the standard deviation sigma
+// and an amount of 100%.
+func Sharpen(img image.Image, sigma float64) image.Image {
+	return UnsharpMask(img, sigma, 1, 0)
+}
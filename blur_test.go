@@ -0,0 +1,72 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestGaussianKernel(t *testing.T) {
+	kernel := gaussianKernel(1)
+	if len(kernel) != 7 {
+		t.Fatalf("want 7 weights, got %d", len(kernel))
+	}
+	var sum float64
+	for i, w := range kernel {
+		sum += w
+		if w != kernel[len(kernel)-1-i] {
+			t.Errorf("want symmetric kernel, got %v", kernel)
+		}
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("want normalized kernel, got sum %g", sum)
+	}
+}
+
+func TestGaussianBlur(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 9, 9))
+	for i := 3; i < len(src.Pix); i += 4 {
+		src.Pix[i] = 0xff
+	}
+	src.SetNRGBA(4, 4, color.NRGBA{255, 255, 255, 255})
+
+	img := GaussianBlur(src, 1).(*image.NRGBA)
+	center, near, far := img.NRGBAAt(4, 4), img.NRGBAAt(5, 4), img.NRGBAAt(8, 4)
+	if center.R >= 255 || center.R <= near.R || near.R <= far.R || center.A != 255 {
+		t.Errorf("want spread peak, got %v, %v, %v", center, near, far)
+	}
+	if img.NRGBAAt(5, 4) != img.NRGBAAt(3, 4) || img.NRGBAAt(4, 5) != img.NRGBAAt(4, 3) {
+		t.Error("want symmetric blur")
+	}
+
+	// Transparent pixels do not darken the edges of opaque areas.
+	src = image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	src.SetNRGBA(1, 0, color.NRGBA{255, 0, 0, 255})
+	if c := GaussianBlur(src, 1).(*image.NRGBA).NRGBAAt(2, 0); c.R != 255 || c.A == 0 || c.A == 255 {
+		t.Errorf("want red with partial alpha, got %v", c)
+	}
+}
+
+func TestUnsharpMask(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 8, 1))
+	for x := range 8 {
+		v := uint8(100)
+		if x >= 4 {
+			v = 150
+		}
+		src.SetNRGBA(x, 0, color.NRGBA{v, v, v, 255})
+	}
+
+	img := Sharpen(src, 1).(*image.NRGBA)
+	if dark, light := img.NRGBAAt(3, 0), img.NRGBAAt(4, 0); dark.R >= 100 || light.R <= 150 || dark.A != 255 {
+		t.Errorf("want increased edge contrast, got %v, %v", dark, light)
+	}
+	if c := img.NRGBAAt(0, 0); c.R != 100 {
+		t.Errorf("want flat area unchanged, got %v", c)
+	}
+	if img := UnsharpMask(src, 1, 1, 100); img.At(3, 0) != src.At(3, 0) {
+		t.Errorf("want edge below threshold unchanged, got %v", img.At(3, 0))
+	}
+}
@@ -0,0 +1,252 @@
+package imgconv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+)
+
+const markerAPP14 = 0xffee
+
+// adobeHeader is the APP14 segment data written by Adobe applications with the
+// "Unknown" transform, meaning the four components are stored as inverted CMYK.
+var adobeHeader = []byte("Adobe\x00\x64\x00\x00\x00\x00\x00")
+
+// decodeImage decodes an image from r. The standard JPEG decoder only accepts four
+// component images with an Adobe APP14 segment, and assumes their values are
+// inverted as written by Adobe applications. Four component images without it are
+// stored as regular CMYK, so the segment is inserted before decoding and the
+// decoded values are inverted back.
+func decodeImage(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	r = br
+	var regularCMYK bool
+	if b, _ := br.Peek(len(jpegMagic)); bytes.Equal(b, jpegMagic) {
+		r, regularCMYK = fixCMYKJPEG(br)
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	if cmyk, ok := img.(*image.CMYK); ok && regularCMYK {
+		for y := range cmyk.Rect.Dy() {
+			row := cmyk.Pix[y*cmyk.Stride : y*cmyk.Stride+cmyk.Rect.Dx()*4]
+			for i := range row {
+				row[i] = 255 - row[i]
+			}
+		}
+	}
+	return img, nil
+}
+
+// fixCMYKJPEG reads the JPEG markers of r up to the frame header. It returns a
+// reader of the whole image, with an Adobe APP14 segment inserted if the image has
+// four components without it.
+func fixCMYKJPEG(r io.Reader) (io.Reader, bool) {
+	header := make([]byte, len(jpegMagic))
+	io.ReadFull(r, header)
+	var adobe bool
+	for {
+		var marker [4]byte
+		if n, err := io.ReadFull(r, marker[:]); err != nil || marker[0] != 0xff {
+			return io.MultiReader(bytes.NewReader(header), bytes.NewReader(marker[:n]), r), false
+		}
+		header = append(header, marker[:]...)
+		size := int(binary.BigEndian.Uint16(marker[2:]))
+		if size < 2 {
+			return io.MultiReader(bytes.NewReader(header), r), false
+		}
+		data := make([]byte, size-2)
+		n, _ := io.ReadFull(r, data)
+		header = append(header, data[:n]...)
+		if n < len(data) {
+			return bytes.NewReader(header), false
+		}
+
+		switch m := binary.BigEndian.Uint16(marker[:]); {
+		case m == markerAPP14 && bytes.HasPrefix(data, adobeHeader[:5]):
+			adobe = true
+		case m == markerSOS:
+			return io.MultiReader(bytes.NewReader(header), r), false
+		case m >= 0xffc0 && m <= 0xffcf && m != 0xffc4 && m != 0xffc8 && m != 0xffcc:
+			// Start of frame, data[5] is the number of components.
+			if adobe || len(data) < 6 || data[5] != 4 {
+				return io.MultiReader(bytes.NewReader(header), r), false
+			}
+			app14 := binary.BigEndian.AppendUint16(nil, markerAPP14)
+			app14 = binary.BigEndian.AppendUint16(app14, uint16(len(adobeHeader)+2))
+			app14 = append(app14, adobeHeader...)
+			return io.MultiReader(bytes.NewReader(jpegMagic), bytes.NewReader(app14),
+				bytes.NewReader(header[len(jpegMagic):]), r), true
+		}
+	}
+}
+
+// iccLUT holds the A2B0 transform of an ICC profile based on lookup tables
+// (lut8Type or lut16Type), from device values to the connection space.
+type iccLUT struct {
+	in, out  int
+	grid     int
+	inTable  [][]float64
+	clut     []float64
+	outTable [][]float64
+	lab      bool
+	lut8     bool
+}
+
+// parseICCLUT parses a lut8Type or lut16Type table with in input channels.
+func parseICCLUT(b []byte, in int, lab bool) (*iccLUT, error) {
+	if len(b) < 48 {
+		return nil, errInvalidICC
+	}
+	l := &iccLUT{in: int(b[8]), out: int(b[9]), grid: int(b[10]), lab: lab}
+	if l.in != in || l.out != 3 || l.grid < 2 {
+		return nil, errInvalidICC
+	}
+	var inEntries, outEntries, size int
+	var read func(p []byte, i int) float64
+	switch string(b[:4]) {
+	case "mft1":
+		l.lut8 = true
+		inEntries, outEntries, size = 256, 256, 1
+		b = b[48:]
+		read = func(p []byte, i int) float64 { return float64(p[i]) / 255 }
+	case "mft2":
+		if len(b) < 52 {
+			return nil, errInvalidICC
+		}
+		inEntries, outEntries, size = int(binary.BigEndian.Uint16(b[48:])), int(binary.BigEndian.Uint16(b[50:])), 2
+		b = b[52:]
+		read = func(p []byte, i int) float64 { return float64(binary.BigEndian.Uint16(p[i*2:])) / 65535 }
+	default:
+		return nil, errors.New("icc: unsupported lookup table type: " + string(b[:4]))
+	}
+	if inEntries < 2 || outEntries < 2 {
+		return nil, errInvalidICC
+	}
+	points := l.out
+	for range l.in {
+		points *= l.grid
+	}
+	if len(b) < (l.in*inEntries+points+l.out*outEntries)*size {
+		return nil, errInvalidICC
+	}
+	table := func(n, entries int) [][]float64 {
+		t := make([][]float64, n)
+		for i := range t {
+			t[i] = make([]float64, entries)
+			for j := range t[i] {
+				t[i][j] = read(b, j)
+			}
+			b = b[entries*size:]
+		}
+		return t
+	}
+	l.inTable = table(l.in, inEntries)
+	l.clut = table(1, points)[0]
+	l.outTable = table(l.out, outEntries)
+	return l, nil
+}
+
+func lookup(table []float64, x float64) float64 {
+	pos := min(max(x, 0), 1) * float64(len(table)-1)
+	i := min(int(pos), len(table)-2)
+	return table[i] + (table[i+1]-table[i])*(pos-float64(i))
+}
+
+// xyz returns the D50 XYZ values of the device values in [0, 1].
+func (l *iccLUT) xyz(v []float64) (xyz [3]float64) {
+	// Multilinear interpolation over the 2^in corners of the grid cell.
+	var base int
+	frac := make([]float64, l.in)
+	strides := make([]int, l.in)
+	stride := l.out
+	for i := l.in - 1; i >= 0; i-- {
+		pos := lookup(l.inTable[i], v[i]) * float64(l.grid-1)
+		cell := min(int(pos), l.grid-2)
+		frac[i] = pos - float64(cell)
+		base += cell * stride
+		strides[i] = stride
+		stride *= l.grid
+	}
+	var out [3]float64
+	for corner := range 1 << l.in {
+		w, offset := 1.0, base
+		for i := range l.in {
+			if corner>>(l.in-1-i)&1 == 1 {
+				w *= frac[i]
+				offset += strides[i]
+			} else {
+				w *= 1 - frac[i]
+			}
+		}
+		if w != 0 {
+			for o := range out {
+				out[o] += w * l.clut[offset+o]
+			}
+		}
+	}
+	for o := range out {
+		out[o] = lookup(l.outTable[o], out[o])
+	}
+
+	if !l.lab {
+		// 1.0 is encoded as 0x8000.
+		for o := range out {
+			xyz[o] = out[o] * 65535 / 32768
+		}
+		return
+	}
+	var lab [3]float64
+	if l.lut8 {
+		lab = [3]float64{out[0] * 100, out[1]*255 - 128, out[2]*255 - 128}
+	} else {
+		// Legacy 16-bit Lab encoding, 100 is encoded as 0xff00.
+		s := 65535.0 / 65280
+		lab = [3]float64{out[0] * s * 100, out[1]*s*255 - 128, out[2]*s*255 - 128}
+	}
+	f := func(t float64) float64 {
+		if t > 6.0/29 {
+			return t * t * t
+		}
+		return 3 * (6.0 / 29) * (6.0 / 29) * (t - 4.0/29)
+	}
+	fy := (lab[0] + 16) / 116
+	xyz[0] = iccD50[0] * f(fy+lab[1]/500)
+	xyz[1] = iccD50[1] * f(fy)
+	xyz[2] = iccD50[2] * f(fy-lab[2]/200)
+	return
+}
+
+// cmykToSRGB converts a CMYK image to sRGB using the lookup table.
+func (l *iccLUT) cmykToSRGB(src *image.CMYK) *image.NRGBA {
+	dst := image.NewNRGBA(src.Rect.Sub(src.Rect.Min))
+	parallel(0, dst.Rect.Dy(), func(ys <-chan int) {
+		v := make([]float64, 4)
+		var last color.CMYK
+		var res [3]uint8
+		for y := range ys {
+			s := src.Pix[y*src.Stride : y*src.Stride+dst.Rect.Dx()*4]
+			d := dst.Pix[y*dst.Stride : y*dst.Stride+dst.Rect.Dx()*4]
+			for i := 0; i < len(s); i += 4 {
+				if c := (color.CMYK{s[i], s[i+1], s[i+2], s[i+3]}); i == 0 || c != last {
+					for j := range v {
+						v[j] = float64(s[i+j]) / 255
+					}
+					xyz := l.xyz(v)
+					for o := range res {
+						lin := srgbFromXYZ[o][0]*xyz[0] + srgbFromXYZ[o][1]*xyz[1] + srgbFromXYZ[o][2]*xyz[2]
+						res[o] = clamp(srgbEncode(min(max(lin, 0), 1)) * 255)
+					}
+					last = c
+				}
+				d[i], d[i+1], d[i+2], d[i+3] = res[0], res[1], res[2], 0xff
+			}
+		}
+	})
+	return dst
+}
@@ -0,0 +1,188 @@
+package imgconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// cmykJPEG returns a baseline 8x8 JPEG image with four components of the given
+// values, which only uses DC coefficients. If adobe is set, the values are stored
+// inverted with an Adobe APP14 segment.
+func cmykJPEG(c color.CMYK, adobe bool) []byte {
+	values := []uint8{c.C, c.M, c.Y, c.K}
+	var b bytes.Buffer
+	b.Write(jpegMagic)
+	segment := func(marker uint16, data []byte) {
+		binary.Write(&b, binary.BigEndian, []uint16{marker, uint16(len(data) + 2)})
+		b.Write(data)
+	}
+	if adobe {
+		segment(markerAPP14, adobeHeader)
+		for i := range values {
+			values[i] = 255 - values[i]
+		}
+	}
+	segment(0xffdb, append([]byte{0}, bytes.Repeat([]byte{1}, 64)...))
+	segment(0xffc0, []byte{8, 0, 8, 0, 8, 4, 1, 0x11, 0, 2, 0x11, 0, 3, 0x11, 0, 4, 0x11, 0})
+	// DC table with 4-bit codes for categories 0-11, AC table with EOB only.
+	segment(0xffc4, append([]byte{0x00, 0, 0, 0, 12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11))
+	segment(0xffc4, []byte{0x10, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	segment(0xffda, []byte{4, 1, 0, 2, 0, 3, 0, 4, 0, 0, 63, 0})
+
+	var acc uint32
+	var n uint
+	write := func(v uint32, bits uint) {
+		acc, n = acc<<bits|v, n+bits
+		for n >= 8 {
+			c := byte(acc >> (n - 8))
+			b.WriteByte(c)
+			if c == 0xff {
+				b.WriteByte(0)
+			}
+			n -= 8
+		}
+	}
+	for _, v := range values {
+		// A DC coefficient of 8 shifts all pixels by 1.
+		dc := (int(v) - 128) * 8
+		cat := uint(0)
+		for a := max(dc, -dc); a > 0; a >>= 1 {
+			cat++
+		}
+		write(uint32(cat), 4)
+		if dc < 0 {
+			dc += 1<<cat - 1
+		}
+		write(uint32(dc), cat)
+		write(0, 1)
+	}
+	write(1<<(8-n)-1, 8-n)
+	binary.Write(&b, binary.BigEndian, uint16(markerEOI))
+	return b.Bytes()
+}
+
+// cmykProfile returns a CMYK profile with a lut16Type A2B0 table of two grid
+// points, where each ink removes its complementary light.
+func cmykProfile() []byte {
+	header := make([]byte, 128)
+	copy(header[8:], []byte{2, 0x10, 0, 0})
+	copy(header[12:], "prtrCMYKLab ")
+	copy(header[36:], "acsp")
+
+	lut := []byte("mft2\x00\x00\x00\x00\x04\x03\x02\x00")
+	for i := range 9 {
+		v := 0
+		if i%4 == 0 {
+			v = 0x10000
+		}
+		lut = binary.BigEndian.AppendUint32(lut, uint32(v))
+	}
+	lut = binary.BigEndian.AppendUint16(lut, 2)
+	lut = binary.BigEndian.AppendUint16(lut, 2)
+	for range 4 {
+		lut = append(lut, 0, 0, 0xff, 0xff)
+	}
+	for i := range 16 {
+		c, m, y, k := i>>3&1, i>>2&1, i>>1&1, i&1
+		// Lab of the red, green and blue lights left, approximated by sRGB.
+		rgb := [3]float64{float64(1 - max(c, k)), float64(1 - max(m, k)), float64(1 - max(y, k))}
+		var xyz [3]float64
+		for j := range xyz {
+			for l := range rgb {
+				xyz[j] += srgbColorants[j][l] * rgb[l]
+			}
+		}
+		f := func(t float64) float64 {
+			if t > 216.0/24389 {
+				return math.Cbrt(t)
+			}
+			return (24389.0/27*t + 16) / 116
+		}
+		fx, fy, fz := f(xyz[0]/iccD50[0]), f(xyz[1]/iccD50[1]), f(xyz[2]/iccD50[2])
+		// Legacy 16-bit Lab encoding.
+		for _, v := range []float64{(116*fy - 16) / 100, (500*(fx-fy) + 128) / 255, (200*(fy-fz) + 128) / 255} {
+			lut = binary.BigEndian.AppendUint16(lut, uint16(math.Round(v*65280)))
+		}
+	}
+	for range 3 {
+		lut = append(lut, 0, 0, 0xff, 0xff)
+	}
+	return appendICCTags(header, []string{"A2B0"}, [][]byte{lut})
+}
+
+func TestDecodeCMYK(t *testing.T) {
+	want := color.CMYK{200, 40, 0, 30}
+	for _, adobe := range []bool{false, true} {
+		img, err := Decode(bytes.NewReader(cmykJPEG(want, adobe)))
+		if err != nil {
+			t.Fatal(adobe, err)
+		}
+		cmyk, ok := img.(*image.CMYK)
+		if !ok {
+			t.Fatalf("want *image.CMYK, got %T", img)
+		}
+		got := cmyk.CMYKAt(4, 4)
+		for i, v := range []uint8{got.C - want.C, got.M - want.M, got.Y - want.Y, got.K - want.K} {
+			if v > 1 && v < 255 {
+				t.Errorf("adobe %v: wrong channel %d: want %v, got %v", adobe, i, want, got)
+			}
+		}
+	}
+
+	b, err := embedJPEG(cmykJPEG(color.CMYK{255, 0, 0, 0}, false), nil, nil, cmykProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := Decode(bytes.NewReader(b), ConvertToSRGB(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := img.At(0, 0).(color.NRGBA); !ok || c.R > 2 || c.G < 253 || c.B < 253 {
+		t.Errorf("want cyan converted to sRGB, got %v", img.At(0, 0))
+	}
+}
+
+func TestCMYKProfile(t *testing.T) {
+	p, err := parseICCProfile(cmykProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := image.NewCMYK(image.Rect(0, 0, 4, 1))
+	for i, c := range []color.CMYK{{0, 0, 0, 0}, {0, 0, 0, 255}, {255, 0, 0, 0}, {0, 255, 255, 0}} {
+		src.SetCMYK(i, 0, c)
+	}
+	dst, _ := p.toSRGB(src)
+	img, ok := dst.(*image.NRGBA)
+	if !ok {
+		t.Fatalf("want *image.NRGBA, got %T", img)
+	}
+	for i, want := range []color.NRGBA{{255, 255, 255, 255}, {0, 0, 0, 255}, {0, 255, 255, 255}, {255, 0, 0, 255}} {
+		got := img.NRGBAAt(i, 0)
+		for j, v := range []uint8{got.R - want.R, got.G - want.G, got.B - want.B} {
+			if v > 2 && v < 254 {
+				t.Errorf("%d: wrong channel %d: want %v, got %v", i, j, want, got)
+			}
+		}
+	}
+
+	if _, ok := p.toSRGB(image.NewNRGBA(image.Rect(0, 0, 1, 1))); ok {
+		t.Error("want RGB image unchanged")
+	}
+
+	// Tables with other input channels or too large grids are rejected.
+	for _, typ := range []string{"mft1", "mft2"} {
+		for _, in := range []byte{9, 4} {
+			lut := append([]byte(typ+"\x00\x00\x00\x00"), in, 3, 255, 0)
+			lut = append(lut, make([]byte, 36)...)
+			lut = append(lut, 0, 2, 0, 2)
+			lut = append(lut, make([]byte, 1024)...)
+			if _, err := parseICCLUT(lut, 4, true); err == nil {
+				t.Errorf("%s: want error for %d input channels with 255 grid points", typ, in)
+			}
+		}
+	}
+}
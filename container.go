@@ -0,0 +1,318 @@
+package imgconv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"slices"
+)
+
+var (
+	jpegMagic   = []byte{0xff, 0xd8}
+	pngMagic    = []byte("\x89PNG\r\n\x1a\n")
+	tiffMagicLE = []byte("II*\x00")
+	tiffMagicBE = []byte("MM\x00*")
+	exifHeader  = []byte("Exif\x00\x00")
+	xmpHeader   = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iccHeader   = []byte("ICC_PROFILE\x00")
+
+	// xmpExtHeader starts the APP1 segments holding the extended XMP packet of
+	// JPEG images, which is split into chunks after a GUID, the full length and
+	// the chunk offset.
+	xmpExtHeader = []byte("http://ns.adobe.com/xmp/extension/\x00")
+)
+
+const (
+	markerAPP1 = 0xffe1
+	markerAPP2 = 0xffe2
+	markerSOS  = 0xffda
+	markerEOI  = 0xffd9
+)
+
+const pngXMPKeyword = "XML:com.adobe.xmp"
+
+// maxMetadataSize limits the size of a metadata block that is read into memory.
+const maxMetadataSize = 16 << 20
+
+// scanMetadata walks the image container read from r and calls fn for each metadata
+// block found, with a reader of the block data. EXIF blocks are passed positioned at
+// their TIFF header; for TIFF containers this is the beginning of the file.
+// Resolution blocks hold the resolution in dots per inch as a big-endian float64.
+// Scanning stops when fn returns false. JPEG, PNG, TIFF, WebP and BMP containers
+// are recognized, scanMetadata reports false for any other data.
+func scanMetadata(r io.Reader, fn func(MetadataKind, io.Reader) bool) bool {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(12)
+	switch {
+	case bytes.HasPrefix(magic, jpegMagic):
+		jpegMetadata(br, fn)
+	case bytes.HasPrefix(magic, pngMagic):
+		pngMetadata(br, fn)
+	case bytes.HasPrefix(magic, tiffMagicLE), bytes.HasPrefix(magic, tiffMagicBE):
+		tiffMetadata(br, fn)
+	case len(magic) == 12 && string(magic[:4]) == "RIFF" && string(magic[8:]) == "WEBP":
+		webpMetadata(br, fn)
+	case bytes.HasPrefix(magic, bmpMagic):
+		bmpMetadata(br, fn)
+	default:
+		return false
+	}
+	return true
+}
+
+// readBlock reads a metadata block of n bytes from r.
+func readBlock(r io.Reader, n int64) ([]byte, bool) {
+	if n < 0 || n > maxMetadataSize {
+		return nil, false
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// emit passes the block b of the given kind to fn. EXIF blocks are checked for
+// a TIFF header after skipping the optional "Exif\0\0" header; invalid blocks are
+// dropped and scanning continues.
+func emit(fn func(MetadataKind, io.Reader) bool, kind MetadataKind, b []byte) bool {
+	if kind == EXIFMetadata {
+		b = bytes.TrimPrefix(b, exifHeader)
+		if !bytes.HasPrefix(b, tiffMagicLE) && !bytes.HasPrefix(b, tiffMagicBE) {
+			return true
+		}
+	}
+	if len(b) == 0 {
+		return true
+	}
+	return fn(kind, bytes.NewReader(b))
+}
+
+// tiffMetadata passes the beginning of a TIFF stream to fn as EXIF block. The fields
+// of a TIFF file may be anywhere in it, so at most maxMetadataSize bytes are read and
+// the block is dropped if it is cut off before its fields.
+func tiffMetadata(r io.Reader, fn func(MetadataKind, io.Reader) bool) {
+	b, err := io.ReadAll(io.LimitReader(r, maxMetadataSize))
+	if err != nil {
+		return
+	}
+	if len(b) == maxMetadataSize {
+		if _, err := parseExif(b); err != nil {
+			return
+		}
+	}
+	emit(fn, EXIFMetadata, b)
+}
+
+// jpegMetadata scans the APP0 (JFIF), APP1 (EXIF and XMP) and APP2 (ICC) segments of a JPEG stream.
+func jpegMetadata(r io.Reader, fn func(MetadataKind, io.Reader) bool) {
+	if _, err := io.CopyN(io.Discard, r, 2); err != nil {
+		return
+	}
+	// ICC profiles may be split across several APP2 segments.
+	var icc [][]byte
+	defer func() {
+		if len(icc) > 0 && !slices.ContainsFunc(icc, func(b []byte) bool { return b == nil }) {
+			emit(fn, ICCMetadata, bytes.Join(icc, nil))
+		}
+	}()
+	for {
+		var marker, size uint16
+		if err := binary.Read(r, binary.BigEndian, &marker); err != nil {
+			return
+		}
+		if marker>>8 != 0xff {
+			return // Invalid JPEG marker.
+		}
+		if marker == markerSOS || marker == markerEOI {
+			return // No more metadata segments.
+		}
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return
+		}
+		if size < 2 {
+			return // Invalid block size.
+		}
+		if marker != markerAPP0 && marker != markerAPP1 && marker != markerAPP2 {
+			if _, err := io.CopyN(io.Discard, r, int64(size-2)); err != nil {
+				return
+			}
+			continue
+		}
+		b, ok := readBlock(r, int64(size-2))
+		if !ok {
+			return
+		}
+		switch {
+		case marker == markerAPP0:
+			if !emitDPI(fn, jfifDPI(b)) {
+				icc = nil
+				return
+			}
+		case marker == markerAPP1 && bytes.HasPrefix(b, exifHeader):
+			if !emit(fn, EXIFMetadata, b) {
+				icc = nil
+				return
+			}
+		case marker == markerAPP1 && bytes.HasPrefix(b, xmpHeader):
+			if !emit(fn, XMPMetadata, b[len(xmpHeader):]) {
+				icc = nil
+				return
+			}
+		case marker == markerAPP2 && bytes.HasPrefix(b, iccHeader) && len(b) > len(iccHeader)+2:
+			seq, count := int(b[len(iccHeader)]), int(b[len(iccHeader)+1])
+			if seq < 1 || seq > count {
+				continue
+			}
+			if len(icc) != count {
+				icc = make([][]byte, count)
+			}
+			icc[seq-1] = b[len(iccHeader)+2:]
+		}
+	}
+}
+
+// pngMetadata scans the eXIf, iCCP, iTXt and pHYs chunks of a PNG stream.
+func pngMetadata(r io.Reader, fn func(MetadataKind, io.Reader) bool) {
+	if _, err := io.CopyN(io.Discard, r, int64(len(pngMagic))); err != nil {
+		return
+	}
+	for {
+		var length uint32
+		var typ [4]byte
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(r, typ[:]); err != nil {
+			return
+		}
+		var kind MetadataKind
+		switch string(typ[:]) {
+		case "eXIf":
+			kind = EXIFMetadata
+		case "iCCP":
+			kind = ICCMetadata
+		case "iTXt":
+			kind = XMPMetadata
+		case "pHYs":
+			kind = DPIMetadata
+		case "IEND":
+			return
+		default:
+			// Skip chunk data and CRC.
+			if _, err := io.CopyN(io.Discard, r, int64(length)+4); err != nil {
+				return
+			}
+			continue
+		}
+		b, ok := readBlock(r, int64(length))
+		if !ok {
+			return
+		}
+		if _, err := io.CopyN(io.Discard, r, 4); err != nil {
+			return
+		}
+		switch kind {
+		case ICCMetadata:
+			b = pngICCProfile(b)
+		case XMPMetadata:
+			b = pngXMP(b)
+		case DPIMetadata:
+			if !emitDPI(fn, pngDPI(b)) {
+				return
+			}
+			continue
+		}
+		if b != nil && !emit(fn, kind, b) {
+			return
+		}
+	}
+}
+
+// pngICCProfile returns the decompressed profile of an iCCP chunk.
+func pngICCProfile(b []byte) []byte {
+	name, data, ok := bytes.Cut(b, []byte{0})
+	if !ok || len(name) == 0 || len(data) < 1 || data[0] != 0 {
+		return nil
+	}
+	return inflate(data[1:])
+}
+
+// pngXMP returns the XMP packet of an iTXt chunk, or nil if it holds other text.
+func pngXMP(b []byte) []byte {
+	keyword, b, ok := bytes.Cut(b, []byte{0})
+	if !ok || string(keyword) != pngXMPKeyword || len(b) < 2 {
+		return nil
+	}
+	compressed := b[0] == 1
+	// Skip compression flag and method, language tag and translated keyword.
+	if _, b, ok = bytes.Cut(b[2:], []byte{0}); !ok {
+		return nil
+	}
+	if _, b, ok = bytes.Cut(b, []byte{0}); !ok {
+		return nil
+	}
+	if compressed {
+		return inflate(b)
+	}
+	return b
+}
+
+func inflate(b []byte) []byte {
+	zr, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+	b, err = io.ReadAll(io.LimitReader(zr, maxMetadataSize))
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// webpMetadata scans the EXIF, XMP and ICCP chunks of a WebP stream.
+func webpMetadata(r io.Reader, fn func(MetadataKind, io.Reader) bool) {
+	if _, err := io.CopyN(io.Discard, r, 12); err != nil {
+		return
+	}
+	for {
+		var fourCC [4]byte
+		var size uint32
+		if _, err := io.ReadFull(r, fourCC[:]); err != nil {
+			return
+		}
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return
+		}
+		var kind MetadataKind
+		switch string(fourCC[:]) {
+		case "EXIF":
+			kind = EXIFMetadata
+		case "XMP ":
+			kind = XMPMetadata
+		case "ICCP":
+			kind = ICCMetadata
+		default:
+			// Chunks are padded to an even size.
+			if _, err := io.CopyN(io.Discard, r, int64(size)+int64(size&1)); err != nil {
+				return
+			}
+			continue
+		}
+		b, ok := readBlock(r, int64(size))
+		if !ok {
+			return
+		}
+		if size&1 != 0 {
+			if _, err := io.CopyN(io.Discard, r, 1); err != nil {
+				return
+			}
+		}
+		if !emit(fn, kind, b) {
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package imgconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// exifOrientation returns a little-endian TIFF structure holding only the orientation tag.
+func exifOrientation(o orientation) []byte {
+	var b bytes.Buffer
+	b.Write(tiffMagicLE)
+	binary.Write(&b, binary.LittleEndian, uint32(8))
+	binary.Write(&b, binary.LittleEndian, uint16(1))
+	binary.Write(&b, binary.LittleEndian, []uint16{0x0112, 3})
+	binary.Write(&b, binary.LittleEndian, uint32(1))
+	binary.Write(&b, binary.LittleEndian, []uint16{uint16(o), 0})
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	return b.Bytes()
+}
+
+func jpegWithExif(exif []byte) []byte {
+	var b bytes.Buffer
+	b.Write(jpegMagic)
+	xmp := []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")
+	for _, data := range [][]byte{xmp, append(exifHeader, exif...)} {
+		binary.Write(&b, binary.BigEndian, []uint16{markerAPP1, uint16(len(data) + 2)})
+		b.Write(data)
+	}
+	binary.Write(&b, binary.BigEndian, uint16(markerEOI))
+	return b.Bytes()
+}
+
+// pngWithExif inserts an eXIf chunk after the IHDR chunk of the PNG-encoded image.
+func pngWithExif(t *testing.T, img image.Image, exif []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	ihdrEnd := len(pngMagic) + 8 + 13 + 4
+	return append(append(append([]byte{}, b[:ihdrEnd]...), appendPNGChunk(nil, "eXIf", exif)...), b[ihdrEnd:]...)
+}
+
+func webpWithExif(exif []byte) []byte {
+	var chunks bytes.Buffer
+	chunks.WriteString("VP8L")
+	binary.Write(&chunks, binary.LittleEndian, uint32(5))
+	chunks.Write([]byte{0x2f, 0, 0, 0, 0, 0})
+	chunks.WriteString("EXIF")
+	binary.Write(&chunks, binary.LittleEndian, uint32(len(exif)))
+	chunks.Write(exif)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(chunks.Len()+4))
+	b.WriteString("WEBP")
+	b.Write(chunks.Bytes())
+	return b.Bytes()
+}
+
+func TestReadOrientation(t *testing.T) {
+	exif := exifOrientation(orientationRotate90)
+	for name, data := range map[string][]byte{
+		"jpeg": jpegWithExif(exif),
+		"png":  pngWithExif(t, image.NewGray(image.Rect(0, 0, 2, 1)), exif),
+		"tiff": exif,
+		"webp": webpWithExif(exif),
+	} {
+		if o := readOrientation(bytes.NewReader(data)); o != orientationRotate90 {
+			t.Errorf("%s: want orientation %d, got %d", name, orientationRotate90, o)
+		}
+	}
+	for name, data := range map[string][]byte{
+		"empty": nil,
+		"jpeg":  jpegWithExif(nil),
+		"png":   pngWithExif(t, image.NewGray(image.Rect(0, 0, 2, 1)), nil),
+		"webp":  webpWithExif(nil),
+		"text":  []byte("Hello"),
+	} {
+		if o := readOrientation(bytes.NewReader(data)); o != orientationUnspecified {
+			t.Errorf("%s: want unspecified orientation, got %d", name, o)
+		}
+	}
+}
+
+func TestDecodeOrientation(t *testing.T) {
+	data := pngWithExif(t, image.NewGray(image.Rect(0, 0, 2, 1)), exifOrientation(orientationRotate90))
+
+	img, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(1, 2) {
+		t.Errorf("want auto-oriented size (1,2), got %v", size)
+	}
+
+	img, err = Decode(bytes.NewReader(data), AutoOrientation(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(2, 1) {
+		t.Errorf("want original size (2,1), got %v", size)
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 type decodeConfig struct {
 	autoOrientation bool
+	metadata        *Metadata
+	toSRGB          bool
 }
 
 var defaultDecodeConfig = decodeConfig{
@@ -26,6 +28,28 @@ func AutoOrientation(enabled bool) DecodeOption {
 	}
 }
 
+// ConvertToSRGB returns a DecodeOption that converts the decoded pixels to sRGB
+// using the embedded ICC profile (if present). Matrix/TRC profiles of RGB and gray
+// images, and lookup table profiles of CMYK images are supported, images with other
+// profiles are left unchanged. Without it, CMYK images are converted to RGB with
+// the naive formula of the color.CMYK type.
+// If the pixels are converted, the ICC profile stored by ExtractMetadata is
+// replaced with an sRGB profile.
+func ConvertToSRGB(enabled bool) DecodeOption {
+	return func(c *decodeConfig) {
+		c.toSRGB = enabled
+	}
+}
+
+// ExtractMetadata returns a DecodeOption that stores the EXIF, XMP and ICC metadata
+// of the decoded image in md. If the image is transformed by auto-orientation, the
+// orientation of the stored EXIF metadata is reset to 1 (normal).
+func ExtractMetadata(md *Metadata) DecodeOption {
+	return func(c *decodeConfig) {
+		c.metadata = md
+	}
+}
+
 // Decode reads an image from r.
 // If want to use custom image format packages which were registered in image package, please
 // make sure these custom packages imported before importing imgconv package.
@@ -35,7 +59,7 @@ func Decode(r io.Reader, opts ...DecodeOption) (image.Image, error) {
 		option(&cfg)
 	}
 
-	return decode(r, autoOrientation(cfg.autoOrientation))
+	return decode(r, func(c *decodeConfig) { *c = cfg })
 }
 
 // DecodeConfig decodes the color model and dimensions of an image that has been encoded in a
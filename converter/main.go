@@ -8,6 +8,7 @@ import (
 	"image"
 	"image/color"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync/atomic"
@@ -30,24 +31,79 @@ var (
 	pdf               = flag.Bool("pdf", false, "")
 	whiteBackground   = flag.Bool("white-background", false, "")
 	gray              = flag.Bool("gray", false, "")
+	grayAlpha         = flag.Bool("gray-alpha", false, "")
 	quality           = flag.Int("quality", 75, "")
+	dpi               = flag.Float64("dpi", 0, "")
 	webpCompression   = flag.Int("webp-compression", int(nativewebp.DefaultCompression), "")
 	autoOrientation   = flag.Bool("auto-orientation", false, "")
+	srgb              = flag.Bool("srgb", false, "")
 	useExtendedFormat = flag.Bool("use-extended-format", false, "")
 	watermark         = flag.String("watermark", "", "")
 	opacity           = flag.Uint("opacity", 128, "")
 	random            = flag.Bool("random", false, "")
 	offsetX           = flag.Int("x", 0, "")
 	offsetY           = flag.Int("y", 0, "")
+	deskew            = flag.Bool("deskew", false, "")
+	deskewMax         = flag.Float64("deskew-max", 15, "")
+	trim              = flag.Bool("trim", false, "")
+	trimTolerance     = flag.Uint("trim-tolerance", 0, "")
+	trimPadding       = flag.Int("trim-padding", 0, "")
+	crop              = flag.String("crop", "", "")
+	rotate            = flag.Float64("rotate", 0, "")
+	rotateCrop        = flag.Bool("rotate-crop", false, "")
 	width             = flag.Int("width", 0, "")
 	height            = flag.Int("height", 0, "")
 	percent           = flag.Float64("percent", 0, "")
+	noUpscale         = flag.Bool("no-upscale", false, "")
+	linearLight       = flag.Bool("linear-light", false, "")
+	autoLevels        = flag.Bool("auto-levels", false, "")
+	autoLevelsClip    = flag.Float64("auto-levels-clip", 0.5, "")
+	autoLevelsChannel = flag.Bool("auto-levels-channels", false, "")
+	equalize          = flag.Bool("equalize", false, "")
+	clahe             = flag.Bool("clahe", false, "")
+	claheTiles        = flag.Int("clahe-tiles", 8, "")
+	claheClip         = flag.Float64("clahe-clip", 2, "")
+	brightness        = flag.Float64("brightness", 0, "")
+	contrast          = flag.Float64("contrast", 0, "")
+	gamma             = flag.Float64("gamma", 1, "")
+	saturation        = flag.Float64("saturation", 0, "")
+	hue               = flag.Float64("hue", 0, "")
+	sepia             = flag.Bool("sepia", false, "")
+	invert            = flag.Bool("invert", false, "")
+	posterize         = flag.Int("posterize", 0, "")
+	colorToAlpha      = flag.String("color-to-alpha", "", "")
+	colorTolerance    = flag.Uint("color-tolerance", 0, "")
+	blur              = flag.Float64("blur", 0, "")
+	sharpen           = flag.Float64("sharpen", 0, "")
+	sharpenAmount     = flag.Float64("sharpen-amount", 1, "")
+	sharpenThreshold  = flag.Uint("sharpen-threshold", 0, "")
+	vignette          = flag.Float64("vignette", 0, "")
+	vignetteRadius    = flag.Float64("vignette-radius", 0.5, "")
+	pad               = flag.String("pad", "", "")
+	padAspect         = flag.String("pad-aspect", "", "")
+	roundCorners      = flag.Float64("round-corners", 0, "")
+	circle            = flag.Bool("circle", false, "")
+	bilevel           = flag.Bool("bilevel", false, "")
+	thresholdLevel    = flag.Uint("threshold-level", 128, "")
+	thresholdWindow   = flag.Int("threshold-window", 25, "")
+	thresholdK        = flag.Float64("threshold-k", 0, "")
 	worker            = flag.Int("worker", 5, "")
 	quiet             = flag.Bool("q", false, "")
 	debug             = flag.Bool("debug", false, "")
 
 	format          imgconv.Format
 	tiffCompression imgconv.TIFFCompression
+	metadata        imgconv.MetadataKind
+	scrub           imgconv.ScrubMode
+	keepTags        imgconv.EXIFTags
+	cropAnchor      imgconv.Anchor
+	flip            imgconv.FlipMode
+	fit             imgconv.ResizeMode
+	fitAnchor       imgconv.Anchor
+	filter          imgconv.ResampleFilter
+	padAnchor       imgconv.Anchor
+	grayMode        imgconv.GrayMode
+	threshold       imgconv.ThresholdMethod
 )
 
 func usage() {
@@ -69,16 +125,32 @@ func usage() {
 		use white color for transparent background (default: false)
   --gray
 		convert to grayscale (default: false)
+  --gray-mode
+		grayscale weighting (rec601, rec709, average, lightness, red, green, blue, default: rec601)
+  --gray-alpha
+		keep the alpha channel of grayscale images (default: false)
   --quality
 		set jpeg or pdf quality (range 1-100, default: 75)
+  --dpi
+		set output resolution in dots per inch for jpeg, png, tiff and bmp, overrides kept dpi metadata
   --tiff-compression
 		set tiff compression type (none, deflate, default: deflate)
   --webp-compression
 		set webp compression level (0-6, default: 4)
   --auto-orientation
 		auto orientation (default: false)
+  --srgb
+		convert colors to sRGB using the embedded ICC profile (default: false)
   --use-extended-format
 		set webp to use extended format (default: false)
+  --metadata
+		keep metadata, comma-separated list of exif, xmp, icc, dpi or all (default: none)
+  --scrub
+		scrub metadata (none, all, gps, allowlist, default: none)
+		jpeg to jpeg conversions keeping all metadata without other operations or encoding options
+		are rewritten without re-encoding.
+  --keep-tags
+		comma-separated list of exif tags kept in allowlist scrub mode, e.g. Make,Model,Copyright
   --watermark
 		watermark path
   --opacity
@@ -87,12 +159,115 @@ func usage() {
 		random watermark (default: false)
   -x, y
 		fixed watermark center offset X, Y value. Only used in no random mode.
+  --deskew
+		straighten scanned pages, the detected angle is logged in debug mode (default: false)
+  --deskew-max
+		largest skew angle in degrees to detect (default: 15)
+  --trim
+		remove uniform borders of the top-left pixel color (default: false)
+  --trim-tolerance
+		largest channel difference in levels of border pixels (range 0-255, default: 0)
+  --trim-padding
+		border pixels kept around the trimmed content (default: 0)
+  --crop
+		crop to WIDTHxHEIGHT before other operations, e.g. 1200x630
+  --crop-anchor
+		crop anchor (center, top-left, top, top-right, left, right, bottom-left, bottom, bottom-right, default: center)
+  --flip
+		flip image (none, horizontal, vertical, transpose, transverse, default: none)
+  --rotate
+		rotate image by degrees counter-clockwise, the uncovered area is white if white-background is set, otherwise transparent
+  --rotate-crop
+		crop the rotated image to the largest rectangle without uncovered area (default: false)
   --width
 		resize width, if one of width or height is 0, the image aspect ratio is preserved.
   --height
 		resize height, if one of width or height is 0, the image aspect ratio is preserved.
   --percent
-		resize percent, only when both of width and height are 0.`)
+		resize percent, only when both of width and height are 0.
+  --fit
+		resize mode when both of width and height are set (default: exact)
+		exact: stretch to width x height
+		fit: fit inside width x height
+		fill: cover width x height and crop the overflow
+		pad: fit inside width x height and pad, white if white-background is set, otherwise transparent
+  --fit-anchor
+		anchor used to crop in fill mode and to place the image in pad mode (default: center)
+  --no-upscale
+		never enlarge the image when resizing (default: false)
+  --filter
+		resampling filter (lanczos, nearest, box, linear, hermite, catmull-rom, mitchell, gaussian, default: lanczos)
+		nearest keeps the hard edges of pixel art, box and linear are faster for large downscales.
+  --linear-light
+		resize in linear light, keeps fine bright details when downscaling (default: false)
+  --auto-levels
+		stretch levels to the full range (default: false)
+  --auto-levels-clip
+		percentage of the darkest and lightest pixels ignored by auto levels (default: 0.5)
+  --auto-levels-channels
+		stretch each color channel separately, also correcting color casts (default: false)
+  --equalize
+		equalize the luminance histogram (default: false)
+  --clahe
+		contrast-limited adaptive histogram equalization (default: false)
+  --clahe-tiles
+		number of clahe tiles in each dimension (default: 8)
+  --clahe-clip
+		clahe contrast limit (default: 2.0)
+  --brightness
+		adjust brightness by percentage (range -100-100, default: 0)
+  --contrast
+		adjust contrast by percentage (range -100-100, default: 0)
+  --gamma
+		gamma correction, below 1.0 darkens and above 1.0 lightens (default: 1.0)
+  --saturation
+		adjust saturation by percentage (range -100-500, default: 0)
+  --hue
+		shift hue by degrees (range -180-180, default: 0)
+  --sepia
+		apply sepia tone (default: false)
+  --invert
+		invert colors (default: false)
+  --posterize
+		number of levels of each channel (range 2-255, default: 0)
+  --color-to-alpha
+		make pixels of the color transparent, e.g. #ffffff
+  --color-tolerance
+		largest channel difference in levels of color-to-alpha pixels (range 0-255, default: 0)
+  --blur
+		gaussian blur standard deviation in pixels (default: 0)
+  --sharpen
+		unsharp mask radius in pixels, applied after resizing (default: 0)
+  --sharpen-amount
+		unsharp mask amount, 1.0 is 100% (default: 1.0)
+  --sharpen-threshold
+		unsharp mask threshold in levels (range 0-255, default: 0)
+  --vignette
+		darken the corners by percentage (range 0-100, default: 0)
+  --vignette-radius
+		distance from the center where the vignette starts, relative to half the diagonal (range 0-1, default: 0.5)
+  --pad
+		padding in pixels as top,right,bottom,left, vertical,horizontal or a single value,
+		transparent unless --white-background is set
+  --pad-aspect
+		pad to the aspect ratio as width:height, e.g. 1:1
+  --pad-anchor
+		pad-aspect anchor point (center, top-left, top, top-right, left, right, bottom-left, bottom, bottom-right, default: center)
+  --round-corners
+		round the corners with the radius in pixels, transparent unless --white-background is set,
+		the output format is png unless --format is given, white for jpg and pdf (default: 0)
+  --circle
+		mask the image with the largest centered circle, transparent like --round-corners (default: false)
+  --bilevel
+		convert to a 1-bit black and white image, e.g. for document scans (default: false)
+  --threshold
+		bilevel threshold method (fixed, otsu, sauvola, niblack, default: otsu)
+  --threshold-level
+		threshold level of the fixed method (range 0-255, default: 128)
+  --threshold-window
+		local window size of the sauvola and niblack methods (default: 25)
+  --threshold-k
+		k parameter of the sauvola and niblack methods (default: 0.34 and -0.2)`)
 }
 
 func main() {
@@ -124,10 +299,24 @@ func main() {
 	flag.TextVar(&format, "format", imgconv.JPEG, "")
 	flag.TextVar(&tiffCompression, "compression", imgconv.TIFFDeflate, "") // compatibility alias, may be removed in future
 	flag.TextVar(&tiffCompression, "tiff-compression", imgconv.TIFFDeflate, "")
+	flag.TextVar(&metadata, "metadata", imgconv.NoMetadata, "")
+	flag.TextVar(&scrub, "scrub", imgconv.ScrubNone, "")
+	flag.TextVar(&keepTags, "keep-tags", imgconv.EXIFTags(nil), "")
+	flag.TextVar(&cropAnchor, "crop-anchor", imgconv.Center, "")
+	flag.TextVar(&flip, "flip", imgconv.FlipNone, "")
+	flag.TextVar(&fit, "fit", imgconv.ResizeExact, "")
+	flag.TextVar(&fitAnchor, "fit-anchor", imgconv.Center, "")
+	flag.TextVar(&filter, "filter", imgconv.Lanczos, "")
+	flag.TextVar(&grayMode, "gray-mode", imgconv.GrayRec601, "")
+	flag.TextVar(&padAnchor, "pad-anchor", imgconv.Center, "")
+	flag.TextVar(&threshold, "threshold", imgconv.ThresholdOtsu, "")
 	flags.SetConfigFile(filepath.Join(filepath.Dir(self), "config.ini"))
 	flags.Parse()
 
 	log.SetOutput(filepath.Join(filepath.Dir(self), fmt.Sprintf("convert%s.log", time.Now().Format("20060102150405"))), os.Stdout)
+	if *debug {
+		log.SetLevel(slog.LevelDebug)
+	}
 
 	srcInfo, err := os.Stat(*src)
 	if err != nil {
@@ -146,13 +335,13 @@ func main() {
 			pb.Start()
 			workers.Workers(*worker).Run(context.Background(), workers.SliceJob(images, func(_ int, image string) {
 				defer pb.Add(1)
-				if _, err := open(image); err != nil {
+				if _, _, err := open(image); err != nil {
 					pb.Message(fmt.Sprintf("Bad image path=%s error=%s", image, err))
 				}
 			}))
 			pb.Wait()
 		case srcInfo.Mode().IsRegular():
-			if _, err := open(*src); err != nil {
+			if _, _, err := open(*src); err != nil {
 				log.Error("Bad image", "image", *src, "error", err)
 			}
 		default:
@@ -164,8 +353,20 @@ func main() {
 
 	task := imgconv.NewOptions()
 
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	// Masked images are saved as PNG to keep the transparent corners, unless
+	// another format is given.
+	mask := *circle || *roundCorners > 0
+	if mask && !setFlags["format"] {
+		format = imgconv.PNG
+	}
+
 	var opts []imgconv.EncodeOption
-	if format == imgconv.JPEG || format == imgconv.PDF {
+	// Leave the default quality unset, so that JPEG images only scrubbed of
+	// their metadata can be copied without re-encoding.
+	if (format == imgconv.JPEG || format == imgconv.PDF) && setFlags["quality"] {
 		opts = append(opts, imgconv.Quality(*quality))
 	}
 	if format == imgconv.TIFF {
@@ -175,14 +376,19 @@ func main() {
 		opts = append(opts, imgconv.WEBPUseExtendedFormat(*useExtendedFormat))
 		opts = append(opts, imgconv.WEBPCompressionLevel(nativewebp.CompressionLevel(*webpCompression)))
 	}
-	if *whiteBackground {
+	if *dpi > 0 {
+		opts = append(opts, imgconv.DPI(*dpi))
+	}
+	if *whiteBackground || mask && (format == imgconv.JPEG || format == imgconv.PDF) {
 		opts = append(opts, imgconv.BackgroundColor(color.White))
 	}
 	task.SetFormat(format, opts...)
 
 	if *gray {
-		task.SetGray(true)
+		task.SetGrayMode(grayMode, *grayAlpha)
 	}
+	task.SetMetadata(metadata)
+	task.SetScrub(imgconv.ScrubPolicy{Mode: scrub, Allow: keepTags})
 	if *watermark != "" {
 		mark, err := imgconv.Open(*watermark)
 		if err != nil {
@@ -193,8 +399,110 @@ func main() {
 		task.SetWatermark(mark, *opacity)
 		task.Watermark.SetRandom(*random).SetOffset(image.Point{X: *offsetX, Y: *offsetY})
 	}
+	if *deskew {
+		var bg color.Color
+		if *whiteBackground {
+			bg = color.White
+		}
+		task.SetDeskew(*deskewMax, bg)
+	}
+	if *trim {
+		task.SetTrim(uint8(min(*trimTolerance, 255)), *trimPadding)
+	}
+	if *crop != "" {
+		var w, h int
+		if _, err := fmt.Sscanf(*crop, "%dx%d", &w, &h); err != nil || w <= 0 || h <= 0 {
+			log.Error("Bad crop size", "crop", *crop)
+			code = 1
+			return
+		}
+		task.SetCrop(w, h, cropAnchor)
+	}
+	task.SetFlip(flip)
+	if *rotate != 0 {
+		var bg color.Color
+		if *whiteBackground {
+			bg = color.White
+		}
+		task.SetRotate(*rotate, bg).Rotate.SetCrop(*rotateCrop)
+	}
 	if *width != 0 || *height != 0 || *percent != 0 {
 		task.SetResize(*width, *height, *percent)
+		task.Resize.SetMode(fit, fitAnchor).SetFilter(filter).SetLinearLight(*linearLight).SetNoUpscale(*noUpscale)
+		if *whiteBackground {
+			task.Resize.SetBackground(color.White)
+		}
+	}
+
+	if *autoLevels {
+		task.SetAutoLevels(*autoLevelsClip, *autoLevelsChannel)
+	}
+	task.SetEqualize(*equalize)
+	if *clahe {
+		task.SetCLAHE(*claheTiles, *claheClip)
+	}
+	if adjust := (imgconv.AdjustOption{
+		Brightness: *brightness,
+		Contrast:   *contrast,
+		Gamma:      *gamma,
+		Saturation: *saturation,
+		Hue:        *hue,
+	}); adjust != (imgconv.AdjustOption{Gamma: 1}) {
+		task.SetAdjust(adjust)
+	}
+	effect := imgconv.EffectOption{Invert: *invert, Sepia: *sepia, Posterize: *posterize}
+	if *colorToAlpha != "" {
+		if effect.ColorToAlpha, err = parseColor(*colorToAlpha); err != nil {
+			log.Error("Bad color", "color-to-alpha", *colorToAlpha)
+			code = 1
+			return
+		}
+		effect.Tolerance = uint8(min(*colorTolerance, 255))
+	}
+	if effect != (imgconv.EffectOption{}) {
+		task.SetEffect(effect)
+	}
+	if *blur > 0 {
+		task.SetBlur(*blur)
+	}
+	if *sharpen > 0 {
+		task.SetSharpen(*sharpen, *sharpenAmount, uint8(min(*sharpenThreshold, 255)))
+	}
+	if *vignette > 0 {
+		task.SetVignette(*vignette, *vignetteRadius)
+	}
+	if *pad != "" || *padAspect != "" {
+		var margins [4]int
+		if *pad != "" {
+			if margins, err = parseMargins(*pad); err != nil {
+				log.Error("Bad padding", "pad", *pad)
+				code = 1
+				return
+			}
+		}
+		var bg color.Color
+		if *whiteBackground {
+			bg = color.White
+		}
+		task.SetPad(margins[0], margins[1], margins[2], margins[3], bg)
+		if *padAspect != "" {
+			var w, h float64
+			if _, err := fmt.Sscanf(*padAspect, "%g:%g", &w, &h); err != nil || w <= 0 || h <= 0 {
+				log.Error("Bad aspect ratio", "pad-aspect", *padAspect)
+				code = 1
+				return
+			}
+			task.Pad.SetAspect(w/h, padAnchor)
+		}
+	}
+	if *circle {
+		task.SetCircleMask()
+	} else if *roundCorners > 0 {
+		task.SetRoundCorners(*roundCorners)
+	}
+	if *bilevel {
+		task.SetThreshold(threshold, uint8(min(*thresholdLevel, 255)))
+		task.Threshold.Window, task.Threshold.K = *thresholdWindow, *thresholdK
 	}
 
 	dstInfo, err := os.Stat(*dst)
@@ -4,9 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +23,7 @@ import (
 
 var (
 	supported = []string{".jpg", ".jpeg", ".png", ".gif", ".tif", ".tiff", ".bmp", ".webp"}
+	jpegImage = []string{".jpg", ".jpeg"}
 	pdfImage  = []string{".pdf"}
 	tiffImage = []string{".tif", ".tiff"}
 )
@@ -33,17 +38,63 @@ func matchFile(exts []string, file string) bool {
 	return false
 }
 
-func open(file string) (image.Image, error) {
-	img, err := imgconv.Open(file, imgconv.AutoOrientation(*autoOrientation))
+func open(file string) (image.Image, *imgconv.Metadata, error) {
+	opts := []imgconv.DecodeOption{imgconv.AutoOrientation(*autoOrientation), imgconv.ConvertToSRGB(*srgb)}
+	var md *imgconv.Metadata
+	if metadata != imgconv.NoMetadata {
+		md = new(imgconv.Metadata)
+		opts = append(opts, imgconv.ExtractMetadata(md))
+	}
+	img, err := imgconv.Open(file, opts...)
 	if err != nil && matchFile(tiffImage, file) {
 		f, err := os.Open(file)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		defer f.Close()
-		return tiff.Decode(f)
+		img, err := tiff.Decode(f)
+		return img, nil, err
 	}
-	return img, err
+	return img, md, err
+}
+
+// parseMargins parses margins given as top,right,bottom,left, as vertical,horizontal
+// or as a single value for all sides.
+func parseMargins(s string) (margins [4]int, err error) {
+	fields := strings.Split(s, ",")
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		if values[i], err = strconv.Atoi(strings.TrimSpace(field)); err != nil {
+			return
+		} else if values[i] < 0 {
+			err = fmt.Errorf("negative margin: %d", values[i])
+			return
+		}
+	}
+	switch len(values) {
+	case 1:
+		margins = [4]int{values[0], values[0], values[0], values[0]}
+	case 2:
+		margins = [4]int{values[0], values[1], values[0], values[1]}
+	case 4:
+		margins = [4]int(values)
+	default:
+		err = fmt.Errorf("bad number of margins: %d", len(values))
+	}
+	return
+}
+
+// parseColor parses a color given in hex notation as #rrggbb or #rgb.
+func parseColor(s string) (color.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return nil, fmt.Errorf("bad color: %s", s)
+	}
+	return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
 }
 
 func size(file string) (n int64) {
@@ -139,6 +190,48 @@ func walkDir(root string, pdf bool, c chan<- walkerResult) {
 
 var errSkip = errors.New("skip")
 
+// scrubOnly reports whether the image only needs its metadata scrubbed, so that
+// it can be copied without re-encoding. The copy keeps all metadata left by the
+// scrub policy, so the task must keep all metadata kinds and use the default
+// format options to give the same result as re-encoding, except that the copy
+// also keeps the extended XMP packet left by the policy.
+func scrubOnly(task *imgconv.Options, image string) bool {
+	if task.Scrub.Mode == imgconv.ScrubNone || task.Metadata != imgconv.AllMetadata ||
+		task.Format.Format != imgconv.JPEG || len(task.Format.EncodeOption) > 0 || !matchFile(jpegImage, image) {
+		return false
+	}
+	// Any other image operation requires re-encoding.
+	ops := *task
+	ops.Format, ops.Metadata, ops.Scrub = nil, imgconv.NoMetadata, imgconv.ScrubPolicy{}
+	return reflect.ValueOf(ops).IsZero()
+}
+
+func openAndConvert(w io.Writer, task *imgconv.Options, image string) error {
+	img, md, err := open(image)
+	if err != nil {
+		return fmt.Errorf("failed to open image: %w", err)
+	}
+	if task.Deskew != nil {
+		// Deskew here to report the detected angle.
+		var angle float64
+		img, angle = imgconv.Deskew(img, task.Deskew.MaxAngle, task.Deskew.Background)
+		log.Debug("Deskewed image", "image", image, "angle", angle)
+		t := *task
+		t.Deskew = nil
+		task = &t
+	}
+	return task.ConvertWithMetadata(w, img, md)
+}
+
+func scrubJPEG(w io.Writer, policy imgconv.ScrubPolicy, image string) error {
+	f, err := os.Open(image)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return imgconv.ScrubJPEG(w, f, policy)
+}
+
 func convert(task *imgconv.Options, image, output string, force bool) error {
 	if _, err := os.Stat(output); err == nil {
 		if !force {
@@ -151,17 +244,18 @@ func convert(task *imgconv.Options, image, output string, force bool) error {
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return fmt.Errorf("failed to create directory path=%s error=%w", path, err)
 	}
-	img, err := open(image)
-	if err != nil {
-		return fmt.Errorf("failed to open image image=%s error=%w", image, err)
-	}
 	f, err := os.CreateTemp(path, "*.tmp")
 	if err != nil {
 		return fmt.Errorf("failed to create temporary file path=%s error=%w", path, err)
 	}
-	err = task.Convert(f, img)
+	if scrubOnly(task, image) {
+		err = scrubJPEG(f, task.Scrub, image)
+	} else {
+		err = openAndConvert(f, task, image)
+	}
 	f.Close()
 	if err != nil {
+		os.Remove(f.Name())
 		return fmt.Errorf("failed to convert image image=%s error=%w", image, err)
 	}
 	if err = os.Rename(f.Name(), output); err != nil {
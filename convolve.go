@@ -0,0 +1,238 @@
+package imgconv
+
+import (
+	"errors"
+	"image"
+	"math"
+)
+
+// BorderMode defines how pixels outside the image are read by a convolution.
+type BorderMode int
+
+const (
+	// BorderClamp repeats the edge pixels.
+	BorderClamp BorderMode = iota
+	// BorderWrap reads the pixels from the opposite edge.
+	BorderWrap
+	// BorderMirror reflects the image at its edges.
+	BorderMirror
+)
+
+// index maps the coordinate i to the range [0, n).
+func (m BorderMode) index(i, n int) int {
+	if i >= 0 && i < n {
+		return i
+	}
+	switch m {
+	case BorderWrap:
+		i %= n
+		if i < 0 {
+			i += n
+		}
+		return i
+	case BorderMirror:
+		if n == 1 {
+			return 0
+		}
+		period := 2 * (n - 1)
+		if i %= period; i < 0 {
+			i += period
+		}
+		if i >= n {
+			i = period - i
+		}
+		return i
+	}
+	return min(max(i, 0), n-1)
+}
+
+// ConvolveOptions are convolution parameters.
+type ConvolveOptions struct {
+	// Border is the way pixels outside the image are read.
+	Border BorderMode
+	// Normalize divides the kernel by the sum of its values, if not zero.
+	Normalize bool
+	// Abs uses the absolute value of the result, which is needed by edge
+	// detection kernels.
+	Abs bool
+	// Bias is added to the result, in levels (0-255).
+	Bias float64
+	// Alpha convolves the alpha channel too, with the color channels weighted by
+	// alpha. Otherwise only the color channels are convolved and alpha is kept.
+	Alpha bool
+}
+
+var errInvalidKernel = errors.New("kernel must be a non-empty rectangle with odd width and height")
+
+// Convolve applies the convolution kernel to the image. The kernel is given in
+// rows and must have odd width and height, its center is applied to the current
+// pixel. A nil options uses the default options.
+func Convolve(img image.Image, kernel [][]float64, options *ConvolveOptions) (image.Image, error) {
+	if options == nil {
+		options = new(ConvolveOptions)
+	}
+	res, err := convolve(toNRGBA(img), kernel, options)
+	if err != nil {
+		return nil, err
+	}
+	return res.image(options), nil
+}
+
+// convolution holds the unclamped result of a convolution.
+type convolution struct {
+	src  *image.NRGBA
+	vals []float64 // 4 channels per pixel
+}
+
+func convolve(src *image.NRGBA, kernel [][]float64, options *ConvolveOptions) (*convolution, error) {
+	kh := len(kernel)
+	if kh%2 == 0 {
+		return nil, errInvalidKernel
+	}
+	kw := len(kernel[0])
+	for _, row := range kernel {
+		if len(row) != kw || kw%2 == 0 {
+			return nil, errInvalidKernel
+		}
+	}
+	scale := 1.0
+	if options.Normalize {
+		var sum float64
+		for _, row := range kernel {
+			for _, v := range row {
+				sum += v
+			}
+		}
+		if sum != 0 {
+			scale = 1 / sum
+		}
+	}
+
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	res := &convolution{src, make([]float64, w*h*4)}
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			for x := range w {
+				var r, g, b, a float64
+				for ky, row := range kernel {
+					sy := options.Border.index(y+ky-kh/2, h)
+					for kx, k := range row {
+						if k == 0 {
+							continue
+						}
+						sx := options.Border.index(x+kx-kw/2, w)
+						i := sy*src.Stride + sx*4
+						s := src.Pix[i : i+4 : i+4]
+						if options.Alpha {
+							wa := float64(s[3]) / 255 * k
+							r += float64(s[0]) * wa
+							g += float64(s[1]) * wa
+							b += float64(s[2]) * wa
+							a += wa * 255
+						} else {
+							r += float64(s[0]) * k
+							g += float64(s[1]) * k
+							b += float64(s[2]) * k
+						}
+					}
+				}
+				v := res.vals[(y*w+x)*4 : (y*w+x)*4+4 : (y*w+x)*4+4]
+				v[0], v[1], v[2], v[3] = r*scale, g*scale, b*scale, a*scale
+			}
+		}
+	})
+	return res, nil
+}
+
+// image converts the result to an image, applying Abs and Bias.
+func (c *convolution) image(options *ConvolveOptions) *image.NRGBA {
+	w, h := c.src.Rect.Dx(), c.src.Rect.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			d := dst.Pix[y*dst.Stride : y*dst.Stride+w*4]
+			v := c.vals[y*w*4 : (y+1)*w*4]
+			s := c.src.Pix[y*c.src.Stride : y*c.src.Stride+w*4]
+			for i := 0; i < len(d); i += 4 {
+				r, g, b, a := v[i], v[i+1], v[i+2], float64(s[i+3])
+				if options.Alpha {
+					a = v[i+3]
+					if a <= 0 {
+						continue
+					}
+					// Colors were weighted by alpha in [0, 1].
+					r, g, b = r*255/a, g*255/a, b*255/a
+				}
+				if options.Abs {
+					r, g, b = math.Abs(r), math.Abs(g), math.Abs(b)
+				}
+				d[i] = clamp(r + options.Bias)
+				d[i+1] = clamp(g + options.Bias)
+				d[i+2] = clamp(b + options.Bias)
+				d[i+3] = clamp(a)
+			}
+		}
+	})
+	return dst
+}
+
+// gradient returns the gradient magnitude of the image for the horizontal
+// kernel kx and its transpose.
+func gradient(img image.Image, kx [][]float64) image.Image {
+	ky := make([][]float64, len(kx[0]))
+	for i := range ky {
+		ky[i] = make([]float64, len(kx))
+		for j := range ky[i] {
+			ky[i][j] = kx[j][i]
+		}
+	}
+	src := toNRGBA(img)
+	options := new(ConvolveOptions)
+	gx, _ := convolve(src, kx, options)
+	gy, _ := convolve(src, ky, options)
+	for i := range gx.vals {
+		gx.vals[i] = math.Hypot(gx.vals[i], gy.vals[i])
+	}
+	return gx.image(options)
+}
+
+// Sobel returns the gradient magnitude of each color channel of the image using
+// the Sobel operator. Edges are light on a black background.
+func Sobel(img image.Image) image.Image {
+	return gradient(img, [][]float64{
+		{-1, 0, 1},
+		{-2, 0, 2},
+		{-1, 0, 1},
+	})
+}
+
+// Prewitt returns the gradient magnitude of each color channel of the image using
+// the Prewitt operator. Edges are light on a black background.
+func Prewitt(img image.Image) image.Image {
+	return gradient(img, [][]float64{
+		{-1, 0, 1},
+		{-1, 0, 1},
+		{-1, 0, 1},
+	})
+}
+
+// Laplacian returns the absolute Laplacian of each color channel of the image,
+// including diagonal neighbors. Edges are light on a black background.
+func Laplacian(img image.Image) image.Image {
+	res, _ := Convolve(img, [][]float64{
+		{-1, -1, -1},
+		{-1, 8, -1},
+		{-1, -1, -1},
+	}, &ConvolveOptions{Abs: true})
+	return res
+}
+
+// Emboss returns the image with a relief effect, lit from the top left.
+func Emboss(img image.Image) image.Image {
+	res, _ := Convolve(img, [][]float64{
+		{-2, -1, 0},
+		{-1, 1, 1},
+		{0, 1, 2},
+	}, nil)
+	return res
+}
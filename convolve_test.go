@@ -0,0 +1,101 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestBorderMode(t *testing.T) {
+	testCase := []struct {
+		mode BorderMode
+		want []int
+	}{
+		{BorderClamp, []int{0, 0, 0, 1, 2, 3, 3, 3}},
+		{BorderWrap, []int{2, 3, 0, 1, 2, 3, 0, 1}},
+		{BorderMirror, []int{2, 1, 0, 1, 2, 3, 2, 1}},
+	}
+	for _, tc := range testCase {
+		for i, want := range tc.want {
+			if got := tc.mode.index(i-2, 4); got != want {
+				t.Errorf("mode %d: want %d for %d, got %d", tc.mode, want, i-2, got)
+			}
+		}
+	}
+	if got := BorderMirror.index(-3, 1); got != 0 {
+		t.Errorf("want 0, got %d", got)
+	}
+}
+
+func TestConvolve(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{30, 0, 0, 255})
+	src.SetNRGBA(1, 0, color.NRGBA{60, 0, 0, 255})
+	src.SetNRGBA(2, 0, color.NRGBA{90, 0, 0, 128})
+
+	kernel := [][]float64{{1, 1, 1}}
+	testCase := []struct {
+		options *ConvolveOptions
+		want    []uint8
+	}{
+		{&ConvolveOptions{Normalize: true}, []uint8{40, 60, 80}},
+		{&ConvolveOptions{Normalize: true, Border: BorderWrap}, []uint8{60, 60, 60}},
+		{&ConvolveOptions{Normalize: true, Border: BorderMirror}, []uint8{50, 60, 70}},
+		{&ConvolveOptions{Bias: 10}, []uint8{130, 190, 250}},
+	}
+	for i, tc := range testCase {
+		img, err := Convolve(src, kernel, tc.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x, want := range tc.want {
+			if c := img.At(x, 0).(color.NRGBA); c.R != want || c.A != src.NRGBAAt(x, 0).A {
+				t.Errorf("#%d: want %d at %d, got %v", i, want, x, c)
+			}
+		}
+	}
+
+	img, err := Convolve(src, [][]float64{{0, 1, 1}}, &ConvolveOptions{Normalize: true, Alpha: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (60*255 + 90*128) / (255 + 128) and (255 + 128) / 2.
+	if c := img.At(1, 0).(color.NRGBA); c.R != 70 || c.A != 192 {
+		t.Errorf("want alpha weighted color, got %v", c)
+	}
+
+	for _, kernel := range [][][]float64{nil, {{1, 1}}, {{1}, {1}}, {{1, 1, 1}, {1}, {1, 1, 1}}} {
+		if _, err := Convolve(src, kernel, nil); err == nil {
+			t.Errorf("want error for kernel %v", kernel)
+		}
+	}
+}
+
+func TestEdgeDetection(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 6, 6))
+	for y := range 6 {
+		for x := range 6 {
+			v := uint8(0)
+			if x >= 3 {
+				v = 200
+			}
+			src.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	for name, fn := range map[string]func(image.Image) image.Image{
+		"sobel":     Sobel,
+		"prewitt":   Prewitt,
+		"laplacian": Laplacian,
+	} {
+		img := fn(src)
+		if c := img.At(0, 3).(color.NRGBA); c.R != 0 || c.A != 255 {
+			t.Errorf("%s: want black flat area, got %v", name, c)
+		}
+		if c := img.At(2, 3).(color.NRGBA); c.R < 200 {
+			t.Errorf("%s: want light edge, got %v", name, c)
+		}
+	}
+	if c := Emboss(src).At(5, 3).(color.NRGBA); c.R != 200 {
+		t.Errorf("emboss: want flat area unchanged, got %v", c)
+	}
+}
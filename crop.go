@@ -0,0 +1,126 @@
+package imgconv
+
+import (
+	"encoding"
+	"fmt"
+	"image"
+	"strings"
+)
+
+var (
+	_ encoding.TextUnmarshaler = new(Anchor)
+	_ encoding.TextMarshaler   = Anchor(0)
+)
+
+// Anchor is the anchor point for image alignment.
+type Anchor int
+
+const (
+	Center Anchor = iota
+	TopLeft
+	Top
+	TopRight
+	Left
+	Right
+	BottomLeft
+	Bottom
+	BottomRight
+)
+
+var anchors = []string{
+	"center",
+	"top-left",
+	"top",
+	"top-right",
+	"left",
+	"right",
+	"bottom-left",
+	"bottom",
+	"bottom-right",
+}
+
+func (a *Anchor) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	for index, anchor := range anchors {
+		if s == anchor {
+			*a = Anchor(index)
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported anchor: %s", s)
+}
+
+func (a Anchor) MarshalText() ([]byte, error) {
+	if a < 0 || int(a) >= len(anchors) {
+		return []byte("unknown"), nil
+	}
+	return []byte(anchors[a]), nil
+}
+
+// anchorRect returns the rectangle of size width x height aligned to the anchor
+// point of b. The rectangle is not clipped to b.
+func anchorRect(b image.Rectangle, width, height int, anchor Anchor) image.Rectangle {
+	x, y := b.Min.X+(b.Dx()-width)/2, b.Min.Y+(b.Dy()-height)/2
+	switch anchor {
+	case TopLeft, Left, BottomLeft:
+		x = b.Min.X
+	case TopRight, Right, BottomRight:
+		x = b.Max.X - width
+	}
+	switch anchor {
+	case TopLeft, Top, TopRight:
+		y = b.Min.Y
+	case BottomLeft, Bottom, BottomRight:
+		y = b.Max.Y - height
+	}
+	return image.Rect(x, y, x+width, y+height)
+}
+
+// Crop cuts out the rectangular region rect of the image. The region is clipped
+// to the image bounds, and the result bounds start at (0, 0).
+func Crop(img image.Image, rect image.Rectangle) image.Image {
+	r := rect.Intersect(img.Bounds()).Sub(img.Bounds().Min)
+	if r.Empty() {
+		return &image.NRGBA{}
+	}
+	src := newScanner(img)
+	dst := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	rowSize := r.Dx() * 4
+	parallel(r.Min.Y, r.Max.Y, func(ys <-chan int) {
+		for y := range ys {
+			i := (y - r.Min.Y) * dst.Stride
+			src.scan(r.Min.X, y, r.Max.X, y+1, dst.Pix[i:i+rowSize])
+		}
+	})
+	return dst
+}
+
+// CropAnchor cuts out a rectangular region of the specified size from the image
+// using the anchor point. A size larger than the image is limited to the image size.
+func CropAnchor(img image.Image, width, height int, anchor Anchor) image.Image {
+	b := img.Bounds()
+	return Crop(img, anchorRect(b, min(width, b.Dx()), min(height, b.Dy()), anchor))
+}
+
+// CropCenter cuts out a rectangular region of the specified size from the center
+// of the image.
+func CropCenter(img image.Image, width, height int) image.Image {
+	return CropAnchor(img, width, height, Center)
+}
+
+// CropOption is crop option
+type CropOption struct {
+	// Rect is the region to cut out. If it is empty, a region of size
+	// Width x Height aligned to Anchor is used instead.
+	Rect   image.Rectangle
+	Width  int
+	Height int
+	Anchor Anchor
+}
+
+func (c *CropOption) do(base image.Image) image.Image {
+	if !c.Rect.Empty() {
+		return Crop(base, c.Rect)
+	}
+	return CropAnchor(base, c.Width, c.Height, c.Anchor)
+}
@@ -0,0 +1,58 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCrop(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(10, 10, 20, 16))
+	for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+		for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+
+	testCase := []struct {
+		img  image.Image
+		want image.Rectangle
+	}{
+		{Crop(src, image.Rect(12, 11, 15, 13)), image.Rect(12, 11, 15, 13)},
+		{Crop(src, image.Rect(0, 0, 12, 12)), image.Rect(10, 10, 12, 12)},
+		{CropCenter(src, 4, 2), image.Rect(13, 12, 17, 14)},
+		{CropCenter(src, 100, 100), src.Rect},
+		{CropAnchor(src, 3, 2, TopLeft), image.Rect(10, 10, 13, 12)},
+		{CropAnchor(src, 3, 2, Right), image.Rect(17, 12, 20, 14)},
+		{CropAnchor(src, 3, 2, BottomRight), image.Rect(17, 14, 20, 16)},
+		{CropAnchor(src, 3, 2, Bottom), image.Rect(13, 14, 16, 16)},
+	}
+	for i, tc := range testCase {
+		if got := tc.img.Bounds(); got != image.Rect(0, 0, tc.want.Dx(), tc.want.Dy()) {
+			t.Errorf("#%d: want size %v, got %v", i, tc.want.Size(), got)
+			continue
+		}
+		compare(t, src.SubImage(tc.want), tc.img)
+	}
+
+	if img := Crop(src, image.Rect(0, 0, 5, 5)); !img.Bounds().Empty() {
+		t.Errorf("want empty image, got %v", img.Bounds())
+	}
+}
+
+func TestAnchor(t *testing.T) {
+	for i := range anchors {
+		b, _ := Anchor(i).MarshalText()
+		var a Anchor
+		if err := a.UnmarshalText(b); err != nil {
+			t.Fatal(err)
+		}
+		if a != Anchor(i) {
+			t.Errorf("want %d, got %d", i, a)
+		}
+	}
+	var a Anchor
+	if err := a.UnmarshalText([]byte("middle")); err == nil {
+		t.Error("want error for unknown anchor")
+	}
+}
@@ -0,0 +1,128 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// deskewSize is the longest side of the image analyzed to find the skew angle.
+const deskewSize = 1024
+
+// DeskewOption is deskew option
+type DeskewOption struct {
+	// MaxAngle is the largest skew angle in degrees which is detected, 15 if 0.
+	MaxAngle float64
+	// Background is the color of the area uncovered by the rotation, transparent
+	// if nil.
+	Background color.Color
+}
+
+func (d *DeskewOption) do(base image.Image) image.Image {
+	img, _ := Deskew(base, d.MaxAngle, d.Background)
+	return img
+}
+
+// SkewAngle estimates the angle in degrees counter-clockwise by which the image,
+// typically a scanned page of text, must be rotated to make its lines horizontal.
+// Only angles in range (-maxAngle, maxAngle) are searched, 15 if maxAngle is 0.
+// The result has a precision of 0.05 degrees.
+//
+// The angle is found with projection profiles: the dark pixels are projected on
+// the vertical axis of each candidate angle, and the profile with the sharpest
+// transitions between lines of text and the gaps between them wins.
+func SkewAngle(img image.Image, maxAngle float64) float64 {
+	if maxAngle <= 0 {
+		maxAngle = 15
+	}
+	maxAngle = min(maxAngle, 45)
+
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w == 0 || h == 0 {
+		return 0
+	}
+	if w > deskewSize || h > deskewSize {
+		if w > h {
+			w, h = deskewSize, max(h*deskewSize/w, 1)
+		} else {
+			w, h = max(w*deskewSize/h, 1), deskewSize
+		}
+		src = resize(src, w, h, box)
+	}
+	plane, _, _ := lumaPlane(src)
+
+	// The foreground is the minority class, so light text on a dark page works too.
+	level := otsuLevel(plane)
+	var dark int
+	for _, v := range plane {
+		if v < level {
+			dark++
+		}
+	}
+	foreground := func(v uint8) bool { return v < level }
+	if dark > len(plane)/2 {
+		dark = len(plane) - dark
+		foreground = func(v uint8) bool { return v >= level }
+	}
+	if dark == 0 {
+		return 0
+	}
+	points := make([][2]float64, 0, dark)
+	for i, v := range plane {
+		if foreground(v) {
+			points = append(points, [2]float64{float64(i%w) - float64(w)/2, float64(i/w) - float64(h)/2})
+		}
+	}
+
+	skew := bestProjection(points, math.Hypot(float64(w), float64(h)), -maxAngle, maxAngle, 0.5)
+	skew = bestProjection(points, math.Hypot(float64(w), float64(h)), skew-0.5, skew+0.5, 0.05)
+	if angle := -math.Round(skew/0.05) * 0.05; angle != 0 {
+		return angle
+	}
+	return 0
+}
+
+// bestProjection returns the angle in range [from, to] with the given step whose
+// projection profile of the points has the highest score. The points are
+// centered on the image, whose diagonal is diag.
+func bestProjection(points [][2]float64, diag, from, to, step float64) float64 {
+	n := int(math.Round((to-from)/step)) + 1
+	size := int(diag) + 2
+	scores := make([]float64, n)
+	parallel(0, n, func(is <-chan int) {
+		profile := make([]int, size)
+		for i := range is {
+			clear(profile)
+			sin, cos := math.Sincos((from + float64(i)*step) * math.Pi / 180)
+			for _, p := range points {
+				profile[int(p[1]*cos+p[0]*sin+diag/2)]++
+			}
+			var score float64
+			for j := 1; j < size; j++ {
+				d := float64(profile[j] - profile[j-1])
+				score += d * d
+			}
+			scores[i] = score
+		}
+	})
+	var best int
+	for i, score := range scores {
+		// Prefer the smallest angle when scores are equal.
+		if score > scores[best] || score == scores[best] && math.Abs(from+float64(i)*step) < math.Abs(from+float64(best)*step) {
+			best = i
+		}
+	}
+	return from + float64(best)*step
+}
+
+// Deskew straightens the image, typically a scanned page of text, by rotating it
+// by the angle found by SkewAngle, which is returned. The area uncovered by the
+// rotation is filled with bgColor, transparent if nil.
+func Deskew(img image.Image, maxAngle float64, bgColor color.Color) (image.Image, float64) {
+	angle := SkewAngle(img, maxAngle)
+	if angle == 0 {
+		return clone(img), 0
+	}
+	return Rotate(img, angle, bgColor), angle
+}
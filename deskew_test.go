@@ -0,0 +1,85 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// textPage returns a white 400x300 page with lines of black words.
+func textPage() *image.Gray {
+	r := rand.New(rand.NewPCG(1, 2))
+	img := image.NewGray(image.Rect(0, 0, 400, 300))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y := 30; y < 270; y += 20 {
+		for x := 30; x < 370; {
+			word := 10 + r.IntN(30)
+			for yy := y; yy < y+8; yy++ {
+				for xx := x; xx < min(x+word, 370); xx++ {
+					if r.IntN(4) != 0 {
+						img.Pix[yy*img.Stride+xx] = 0
+					}
+				}
+			}
+			x += word + 6
+		}
+	}
+	return img
+}
+
+func TestSkewAngle(t *testing.T) {
+	page := textPage()
+	if angle := SkewAngle(page, 0); angle != 0 {
+		t.Errorf("want 0 for a straight page, got %g", angle)
+	}
+	for _, skew := range []float64{3, -1.5, 7.25} {
+		img := Rotate(page, skew, color.White)
+		if angle := SkewAngle(img, 10); math.Abs(angle+skew) > 0.15 {
+			t.Errorf("want %g, got %g", -skew, angle)
+		}
+	}
+
+	// Light text on a dark page.
+	inverted := clone(Rotate(page, 2, color.White))
+	for i, v := range inverted.Pix {
+		if i%4 != 3 {
+			inverted.Pix[i] = 0xff - v
+		}
+	}
+	if angle := SkewAngle(inverted, 0); math.Abs(angle+2) > 0.15 {
+		t.Errorf("want -2 for an inverted page, got %g", angle)
+	}
+
+	// Large images are analyzed at a smaller size.
+	if angle := SkewAngle(Rotate(resize(page, 1600, 1200, linear), 3, color.White), 0); math.Abs(angle+3) > 0.15 {
+		t.Errorf("want -3 for a large page, got %g", angle)
+	}
+
+	if angle := SkewAngle(image.NewGray(image.Rect(0, 0, 10, 10)), 0); angle != 0 {
+		t.Errorf("want 0 for a blank page, got %g", angle)
+	}
+}
+
+func TestDeskew(t *testing.T) {
+	img, angle := Deskew(Rotate(textPage(), -4, color.White), 0, color.White)
+	if math.Abs(angle-4) > 0.15 {
+		t.Errorf("want 4, got %g", angle)
+	}
+	if angle := SkewAngle(img, 0); math.Abs(angle) > 0.15 {
+		t.Errorf("want straight page, got %g", angle)
+	}
+	if c := color.GrayModel.Convert(img.At(0, 0)).(color.Gray); c.Y != 0xff {
+		t.Errorf("want white corner, got %v", c)
+	}
+
+	page := textPage()
+	img, angle = Deskew(page, 0, nil)
+	if angle != 0 {
+		t.Errorf("want 0, got %g", angle)
+	}
+	compare(t, page, img)
+}
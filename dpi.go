@@ -0,0 +1,155 @@
+package imgconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"slices"
+)
+
+const (
+	inchesPerMeter = 1 / 0.0254
+	inchesPerCM    = 1 / 2.54
+)
+
+var (
+	bmpMagic   = []byte("BM")
+	jfifHeader = []byte("JFIF\x00")
+)
+
+const markerAPP0 = 0xffe0
+
+// emitDPI passes the resolution in dots per inch to fn as a DPIMetadata block.
+func emitDPI(fn func(MetadataKind, io.Reader) bool, dpi float64) bool {
+	if dpi <= 0 || math.IsInf(dpi, 0) || math.IsNaN(dpi) {
+		return true
+	}
+	return emit(fn, DPIMetadata, binary.BigEndian.AppendUint64(nil, math.Float64bits(dpi)))
+}
+
+// jfifDPI returns the resolution of a JFIF APP0 segment.
+func jfifDPI(b []byte) float64 {
+	if len(b) < 12 || !bytes.HasPrefix(b, jfifHeader) {
+		return 0
+	}
+	density := float64(binary.BigEndian.Uint16(b[8:]))
+	switch b[7] {
+	case 1: // dots per inch
+		return density
+	case 2: // dots per centimeter
+		return density / inchesPerCM
+	}
+	return 0 // aspect ratio only
+}
+
+// pngDPI returns the resolution of a pHYs chunk.
+func pngDPI(b []byte) float64 {
+	if len(b) < 9 || b[8] != 1 {
+		return 0 // aspect ratio only
+	}
+	return ppmToDPI(binary.BigEndian.Uint32(b))
+}
+
+// ppmToDPI converts pixels per meter to dots per inch, preferring the integer
+// resolution that was rounded to the same number of pixels per meter.
+func ppmToDPI(ppm uint32) float64 {
+	dpi := float64(ppm) / inchesPerMeter
+	if rounded := math.Round(dpi); math.Round(rounded*inchesPerMeter) == float64(ppm) {
+		return rounded
+	}
+	return dpi
+}
+
+// bmpMetadata reads the resolution from the BITMAPINFOHEADER of a BMP stream.
+func bmpMetadata(r io.Reader, fn func(MetadataKind, io.Reader) bool) {
+	var header [46]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	if binary.LittleEndian.Uint32(header[14:]) < 40 {
+		return // BITMAPCOREHEADER has no resolution.
+	}
+	if ppm := int32(binary.LittleEndian.Uint32(header[38:])); ppm > 0 {
+		emitDPI(fn, ppmToDPI(uint32(ppm)))
+	}
+}
+
+// dpi returns the resolution of the IFD0 fields.
+func (d *exifData) dpi() float64 {
+	x, ok := d.lookupFloat(d.ifd0, tagXResolution)
+	if !ok || x <= 0 || math.IsInf(x, 0) || math.IsNaN(x) {
+		return 0
+	}
+	switch unit, ok := d.lookupUint(d.ifd0, tagResolutionUnit); {
+	case !ok || unit == 2: // inch
+		return x
+	case unit == 3: // centimeter
+		return x / inchesPerCM
+	}
+	return 0
+}
+
+// setDPI sets the resolution fields of IFD0.
+func (d *exifData) setDPI(dpi float64) {
+	num, den := uint32(math.Round(dpi)), uint32(1)
+	if float64(num) != dpi {
+		num, den = uint32(math.Round(dpi*1000)), 1000
+	}
+	for _, tag := range []uint16{tagXResolution, tagYResolution} {
+		d.ifd0 = d.ifd0.set(exifEntry{tag, exifRational, 1, d.order.AppendUint32(d.order.AppendUint32(nil, num), den)})
+	}
+	d.ifd0 = d.ifd0.set(exifEntry{tagResolutionUnit, exifShort, 1, d.order.AppendUint16(nil, 2)})
+}
+
+// embedDPI writes the resolution into the image data b encoded in format f.
+// Formats without resolution support are returned unchanged.
+func embedDPI(f Format, b []byte, dpi float64) ([]byte, error) {
+	switch f {
+	case JPEG:
+		if !bytes.HasPrefix(b, jpegMagic) {
+			return nil, errors.New("jpeg: missing SOI marker")
+		}
+		density := uint16(min(math.Round(dpi), math.MaxUint16))
+		app0 := append(slices.Clone(jfifHeader), 1, 2, 1) // version 1.02, dots per inch
+		app0 = binary.BigEndian.AppendUint16(app0, density)
+		app0 = binary.BigEndian.AppendUint16(app0, density)
+		app0 = append(app0, 0, 0) // no thumbnail
+
+		rest := b[len(jpegMagic):]
+		if len(rest) >= 4 && binary.BigEndian.Uint16(rest) == markerAPP0 && bytes.HasPrefix(rest[4:], jfifHeader) {
+			// Replace the existing JFIF segment.
+			rest = rest[min(len(rest), 2+int(binary.BigEndian.Uint16(rest[2:]))):]
+		}
+		res, err := appendJPEGSegment(append([]byte(nil), jpegMagic...), markerAPP0, app0)
+		if err != nil {
+			return nil, err
+		}
+		return append(res, rest...), nil
+	case PNG:
+		ihdrEnd := len(pngMagic) + 8 + 13 + 4
+		if !bytes.HasPrefix(b, pngMagic) || len(b) < ihdrEnd {
+			return nil, errors.New("png: missing IHDR chunk")
+		}
+		ppm := uint32(math.Round(dpi * inchesPerMeter))
+		phys := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, ppm), ppm)
+		res := appendPNGChunk(append([]byte(nil), b[:ihdrEnd]...), "pHYs", append(phys, 1))
+		return append(res, b[ihdrEnd:]...), nil
+	case TIFF:
+		d, err := parseExif(b)
+		if err != nil {
+			return nil, err
+		}
+		d.setDPI(dpi)
+		return rewriteTIFF(b, d), nil
+	case BMP:
+		if !bytes.HasPrefix(b, bmpMagic) || len(b) < 46 || binary.LittleEndian.Uint32(b[14:]) < 40 {
+			return nil, errors.New("bmp: invalid format")
+		}
+		ppm := uint32(math.Round(dpi * inchesPerMeter))
+		binary.LittleEndian.PutUint32(b[38:], ppm)
+		binary.LittleEndian.PutUint32(b[42:], ppm)
+	}
+	return b, nil
+}
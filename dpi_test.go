@@ -0,0 +1,119 @@
+package imgconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDPI(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for _, format := range []Format{JPEG, PNG, TIFF, BMP} {
+		for _, dpi := range []float64{300, 72.5} {
+			var buf bytes.Buffer
+			if err := (&FormatOption{format, []EncodeOption{DPI(dpi)}}).Encode(&buf, img); err != nil {
+				t.Fatal(format, err)
+			}
+			md, err := ReadMetadata(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(format, err)
+			}
+			want := dpi
+			if format == JPEG {
+				want = math.Round(dpi)
+			}
+			if math.Abs(md.DPI-want) > 0.01 {
+				t.Errorf("%s: want %g dpi, got %g", format, want, md.DPI)
+			}
+			if _, err := Decode(&buf); err != nil {
+				t.Errorf("%s: %v", format, err)
+			}
+		}
+	}
+
+	// EXIF metadata is updated with the resolution.
+	x, err := newEXIF(sampleExif(binary.LittleEndian))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := (&FormatOption{WEBP, []EncodeOption{DPI(240), EmbedMetadata(&Metadata{EXIF: x})}}).Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if md, err := ReadMetadata(&buf); err != nil {
+		t.Fatal(err)
+	} else if md.DPI != 240 {
+		t.Errorf("want 240 dpi, got %g", md.DPI)
+	}
+
+	// TIFF files hold no unreferenced IFDs after embedding metadata and resolution.
+	for _, img := range []image.Image{img, image.NewPaletted(img.Rect, color.Palette{color.Black, color.White})} {
+		buf.Reset()
+		if err := (&FormatOption{TIFF, []EncodeOption{DPI(240), EmbedMetadata(&Metadata{EXIF: x, XMP: []byte("xmp")})}}).Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		d, err := parseExif(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := rewriteTIFF(slices.Clone(buf.Bytes()), d); len(b) != buf.Len() {
+			t.Errorf("%T: want %d bytes, got %d", img, buf.Len(), len(b))
+		}
+		if d.dpi() != 240 {
+			t.Errorf("%T: want 240 dpi, got %g", img, d.dpi())
+		}
+	}
+
+	for _, tc := range []struct {
+		app0 []byte
+		dpi  float64
+	}{
+		{[]byte("JFIF\x00\x01\x02\x01\x01\x2c\x01\x2c\x00\x00"), 300},
+		{[]byte("JFIF\x00\x01\x02\x02\x00\x76\x00\x76\x00\x00"), 299.72},
+		{[]byte("JFIF\x00\x01\x02\x00\x00\x01\x00\x01\x00\x00"), 0},
+	} {
+		if dpi := jfifDPI(tc.app0); math.Abs(dpi-tc.dpi) > 0.01 {
+			t.Errorf("want %g dpi, got %g", tc.dpi, dpi)
+		}
+	}
+}
+
+func TestKeepDPI(t *testing.T) {
+	var src bytes.Buffer
+	if err := (&FormatOption{PNG, []EncodeOption{DPI(150)}}).Encode(&src, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	var md Metadata
+	img, err := Decode(&src, ExtractMetadata(&md))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(md.DPI-150) > 0.01 {
+		t.Fatalf("want 150 dpi, got %g", md.DPI)
+	}
+
+	for _, tc := range []struct {
+		opts *Options
+		dpi  float64
+	}{
+		{NewOptions().SetMetadata(DPIMetadata), 150},
+		{NewOptions().SetMetadata(DPIMetadata).SetFormat(JPEG, DPI(600)), 600},
+		{NewOptions().SetMetadata(EXIFMetadata), 0},
+	} {
+		var buf bytes.Buffer
+		if err := tc.opts.ConvertWithMetadata(&buf, img, &md); err != nil {
+			t.Fatal(err)
+		}
+		res, err := ReadMetadata(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.DPI != tc.dpi {
+			t.Errorf("want %g dpi, got %g", tc.dpi, res.DPI)
+		}
+	}
+}
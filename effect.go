@@ -0,0 +1,123 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+)
+
+// EffectOption is color effect option. The effects which are set are applied in
+// the order of the fields: color to alpha, invert, sepia, duotone and posterize.
+// Use several options with Options.SetEffect to apply them in another order.
+type EffectOption struct {
+	// ColorToAlpha makes the pixels of this color transparent, if not nil.
+	ColorToAlpha color.Color
+	// Tolerance is the largest difference in levels (0-255) of a channel between
+	// a pixel and ColorToAlpha for the pixel to be made transparent.
+	Tolerance uint8
+	Invert    bool
+	Sepia     bool
+	// DuotoneShadow and DuotoneHighlight are the colors of the darkest and
+	// lightest pixels of a duotone image, applied if both are not nil.
+	DuotoneShadow    color.Color
+	DuotoneHighlight color.Color
+	// Posterize is the number of levels of each channel, from 2 to 255. It is
+	// ignored if 0.
+	Posterize int
+}
+
+// Effect applies the color effects of the option to the image.
+func Effect(base image.Image, option *EffectOption) image.Image {
+	return option.do(base)
+}
+
+func (e *EffectOption) do(base image.Image) image.Image {
+	if e.ColorToAlpha != nil {
+		base = ColorToAlpha(base, e.ColorToAlpha, e.Tolerance)
+	}
+	if e.Invert {
+		base = Invert(base)
+	}
+	if e.Sepia {
+		base = Sepia(base)
+	}
+	if e.DuotoneShadow != nil && e.DuotoneHighlight != nil {
+		base = Duotone(base, e.DuotoneShadow, e.DuotoneHighlight)
+	}
+	if e.Posterize > 0 {
+		base = Posterize(base, e.Posterize)
+	}
+	return base
+}
+
+// Invert produces the negative of the image, keeping the alpha.
+func Invert(img image.Image) image.Image {
+	lut := make([]uint8, 256)
+	for i := range lut {
+		lut[i] = 255 - uint8(i)
+	}
+	return adjustLUT(img, lut)
+}
+
+// Sepia gives the image the brown tones of an old photograph.
+func Sepia(img image.Image) image.Image {
+	return adjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		r, g, b := float64(c.R), float64(c.G), float64(c.B)
+		return color.NRGBA{
+			clamp(0.393*r + 0.769*g + 0.189*b),
+			clamp(0.349*r + 0.686*g + 0.168*b),
+			clamp(0.272*r + 0.534*g + 0.131*b),
+			c.A,
+		}
+	})
+}
+
+// Duotone maps the luminance of the image to the gradient from the shadow color
+// for black to the highlight color for white, keeping the alpha.
+func Duotone(img image.Image, shadow, highlight color.Color) image.Image {
+	s := color.NRGBAModel.Convert(shadow).(color.NRGBA)
+	h := color.NRGBAModel.Convert(highlight).(color.NRGBA)
+	var lut [256]color.NRGBA
+	for i := range lut {
+		t := float64(i) / 255
+		lut[i] = color.NRGBA{
+			clamp(float64(s.R)*(1-t) + float64(h.R)*t),
+			clamp(float64(s.G)*(1-t) + float64(h.G)*t),
+			clamp(float64(s.B)*(1-t) + float64(h.B)*t),
+			0,
+		}
+	}
+	return adjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		d := lut[luma(c.R, c.G, c.B)]
+		d.A = c.A
+		return d
+	})
+}
+
+// Posterize reduces each color channel of the image to the number of levels,
+// from 2 to 255, spread evenly between black and white.
+func Posterize(img image.Image, levels int) image.Image {
+	levels = min(max(levels, 2), 255)
+	step := 255 / float64(levels-1)
+	lut := make([]uint8, 256)
+	for i := range lut {
+		lut[i] = clamp(float64(int(float64(i)/step+0.5)) * step)
+	}
+	return adjustLUT(img, lut)
+}
+
+// ColorToAlpha makes the pixels of the image which differ from the color by at
+// most tolerance levels in each channel transparent, such as a flat background.
+func ColorToAlpha(img image.Image, c color.Color, tolerance uint8) image.Image {
+	key := color.NRGBAModel.Convert(c).(color.NRGBA)
+	tol := int(tolerance)
+	within := func(a, b uint8) bool {
+		d := int(a) - int(b)
+		return d <= tol && -d <= tol
+	}
+	return adjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		if within(c.R, key.R) && within(c.G, key.G) && within(c.B, key.B) {
+			return color.NRGBA{}
+		}
+		return c
+	})
+}
@@ -0,0 +1,88 @@
+package imgconv
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEffect(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 255})
+	src.SetNRGBA(1, 0, color.NRGBA{100, 150, 200, 128})
+	src.SetNRGBA(2, 0, color.NRGBA{255, 255, 255, 255})
+
+	img := Invert(src).(*image.NRGBA)
+	if c := img.NRGBAAt(1, 0); c != (color.NRGBA{155, 105, 55, 128}) {
+		t.Errorf("want inverted color, got %v", c)
+	}
+	compare(t, src, Invert(img))
+
+	img = Sepia(src).(*image.NRGBA)
+	if c := img.NRGBAAt(1, 0); c.R <= c.G || c.G <= c.B || c.A != 128 {
+		t.Errorf("want brown tone, got %v", c)
+	}
+	if c := img.NRGBAAt(0, 0); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("want black, got %v", c)
+	}
+
+	navy, gold := color.NRGBA{0, 0, 128, 255}, color.NRGBA{255, 200, 0, 255}
+	img = Duotone(src, navy, gold).(*image.NRGBA)
+	if c := img.NRGBAAt(0, 0); c != navy {
+		t.Errorf("want %v, got %v", navy, c)
+	}
+	if c := img.NRGBAAt(2, 0); c != gold {
+		t.Errorf("want %v, got %v", gold, c)
+	}
+	if c := img.NRGBAAt(1, 0); c.R == 0 || c.B == 0 || c.A != 128 {
+		t.Errorf("want mixed color, got %v", c)
+	}
+
+	img = Posterize(src, 2).(*image.NRGBA)
+	if c := img.NRGBAAt(1, 0); c != (color.NRGBA{0, 255, 255, 128}) {
+		t.Errorf("want 2 levels, got %v", c)
+	}
+	img = Posterize(src, 3).(*image.NRGBA)
+	if c := img.NRGBAAt(1, 0); c != (color.NRGBA{128, 128, 255, 128}) {
+		t.Errorf("want 3 levels, got %v", c)
+	}
+	compare(t, src, Posterize(src, 256))
+
+	img = ColorToAlpha(src, color.NRGBA{250, 250, 250, 255}, 5).(*image.NRGBA)
+	if c := img.NRGBAAt(2, 0); c.A != 0 {
+		t.Errorf("want transparent, got %v", c)
+	}
+	if c := img.NRGBAAt(0, 0); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("want black kept, got %v", c)
+	}
+	if c := ColorToAlpha(src, color.White, 4).(*image.NRGBA).NRGBAAt(2, 0); c.A != 0 {
+		t.Errorf("want transparent, got %v", c)
+	}
+
+	img = Effect(src, &EffectOption{ColorToAlpha: color.White, Invert: true}).(*image.NRGBA)
+	if c := img.NRGBAAt(0, 0); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("want white, got %v", c)
+	}
+	if c := img.NRGBAAt(2, 0); c.A != 0 {
+		t.Errorf("want transparent, got %v", c)
+	}
+	compare(t, src, Effect(src, &EffectOption{DuotoneShadow: navy}))
+
+	// Several options are applied in the given order.
+	var buf bytes.Buffer
+	if err := NewOptions().SetEffect(EffectOption{Invert: true}, EffectOption{ColorToAlpha: color.White}).
+		SetFormat(PNG).Convert(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := res.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("want transparent, got alpha %d", a)
+	}
+	if c := color.NRGBAModel.Convert(res.At(2, 0)); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("want black, got %v", c)
+	}
+}
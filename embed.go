@@ -0,0 +1,312 @@
+package imgconv
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+)
+
+const (
+	maxJPEGSegmentSize = 0xffff - 2
+	maxJPEGICCChunk    = maxJPEGSegmentSize - 14
+)
+
+// WebP VP8X flags.
+const (
+	webpFlagXMP   = 0x04
+	webpFlagEXIF  = 0x08
+	webpFlagAlpha = 0x10
+	webpFlagICC   = 0x20
+)
+
+// embed writes md into the image data b of img encoded in format f. A positive dpi
+// replaces the resolution of the EXIF metadata. Formats without metadata support
+// are returned unchanged.
+func (md *Metadata) embed(f Format, b []byte, img image.Image, dpi float64) ([]byte, error) {
+	bounds := img.Bounds()
+	exif := md.EXIF.exifData(bounds.Dx(), bounds.Dy())
+	if exif != nil && dpi > 0 {
+		exif.setDPI(dpi)
+	}
+	icc := md.ICC
+	if len(icc) < 20 || string(icc[16:20]) != encodedColorSpace(f, img) {
+		// The profile does not apply to the encoded pixels, for example after
+		// a conversion to grayscale or of CMYK images to RGB.
+		icc = nil
+	}
+	if exif == nil && md.XMP == nil && icc == nil {
+		return b, nil
+	}
+	switch f {
+	case JPEG:
+		return embedJPEG(b, exif, md.XMP, icc)
+	case PNG:
+		return embedPNG(b, exif, md.XMP, icc)
+	case TIFF:
+		return embedTIFF(b, exif, md.XMP, icc)
+	case WEBP:
+		return embedWebP(b, exif, md.XMP, icc)
+	}
+	return b, nil
+}
+
+// encodedColorSpace returns the ICC color space signature of img encoded in format f.
+func encodedColorSpace(f Format, img image.Image) string {
+	switch img.(type) {
+	case *image.Gray:
+		if f != WEBP {
+			return "GRAY"
+		}
+	case *image.Gray16:
+		if f == PNG || f == TIFF {
+			return "GRAY"
+		}
+	case *image.Paletted:
+		if _, _, ok := isBilevel(img); ok && f == TIFF {
+			return "GRAY"
+		}
+	}
+	return "RGB "
+}
+
+func appendJPEGSegment(b []byte, marker uint16, data ...[]byte) ([]byte, error) {
+	size := 0
+	for _, d := range data {
+		size += len(d)
+	}
+	if size > maxJPEGSegmentSize {
+		return nil, errors.New("jpeg: metadata segment too large")
+	}
+	b = binary.BigEndian.AppendUint16(b, marker)
+	b = binary.BigEndian.AppendUint16(b, uint16(size+2))
+	for _, d := range data {
+		b = append(b, d...)
+	}
+	return b, nil
+}
+
+// embedJPEG inserts the APP1 (EXIF and XMP) and APP2 (ICC) segments after the SOI marker.
+func embedJPEG(b []byte, exif *exifData, xmp, icc []byte) (res []byte, err error) {
+	if !bytes.HasPrefix(b, jpegMagic) {
+		return nil, errors.New("jpeg: missing SOI marker")
+	}
+	res = append(res, jpegMagic...)
+	// EXIF and XMP blocks too large for a single segment are left out.
+	if exif != nil {
+		if data := exif.encode(); len(exifHeader)+len(data) <= maxJPEGSegmentSize {
+			if res, err = appendJPEGSegment(res, markerAPP1, exifHeader, data); err != nil {
+				return
+			}
+		}
+	}
+	if xmp != nil && len(xmpHeader)+len(xmp) <= maxJPEGSegmentSize {
+		if res, err = appendJPEGSegment(res, markerAPP1, xmpHeader, xmp); err != nil {
+			return
+		}
+	}
+	if icc != nil {
+		count := (len(icc) + maxJPEGICCChunk - 1) / maxJPEGICCChunk
+		if count > 255 {
+			return nil, errors.New("jpeg: ICC profile too large")
+		}
+		for i := range count {
+			chunk := icc[i*maxJPEGICCChunk : min(len(icc), (i+1)*maxJPEGICCChunk)]
+			if res, err = appendJPEGSegment(res, markerAPP2, iccHeader, []byte{byte(i + 1), byte(count)}, chunk); err != nil {
+				return
+			}
+		}
+	}
+	return append(res, b[len(jpegMagic):]...), nil
+}
+
+func appendPNGChunk(b []byte, typ string, data []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(data)))
+	b = append(b, typ...)
+	b = append(b, data...)
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	return binary.BigEndian.AppendUint32(b, crc.Sum32())
+}
+
+// embedPNG inserts the iCCP, eXIf and iTXt chunks after the IHDR chunk.
+func embedPNG(b []byte, exif *exifData, xmp, icc []byte) ([]byte, error) {
+	ihdrEnd := len(pngMagic) + 8 + 13 + 4
+	if !bytes.HasPrefix(b, pngMagic) || len(b) < ihdrEnd || string(b[len(pngMagic)+4:len(pngMagic)+8]) != "IHDR" {
+		return nil, errors.New("png: missing IHDR chunk")
+	}
+	res := append([]byte(nil), b[:ihdrEnd]...)
+	if icc != nil {
+		var buf bytes.Buffer
+		buf.WriteString("ICC Profile\x00\x00")
+		zw := zlib.NewWriter(&buf)
+		zw.Write(icc)
+		zw.Close()
+		res = appendPNGChunk(res, "iCCP", buf.Bytes())
+	}
+	if exif != nil {
+		res = appendPNGChunk(res, "eXIf", exif.encode())
+	}
+	if xmp != nil {
+		// Uncompressed iTXt chunk with empty language tag and translated keyword.
+		res = appendPNGChunk(res, "iTXt", append([]byte(pngXMPKeyword+"\x00\x00\x00\x00\x00"), xmp...))
+	}
+	return append(res, b[ihdrEnd:]...), nil
+}
+
+// embedTIFF rewrites IFD0 with the metadata fields in addition to the fields of the
+// encoded image.
+func embedTIFF(b []byte, exif *exifData, xmp, icc []byte) ([]byte, error) {
+	d, err := parseExif(b)
+	if err != nil {
+		return nil, err
+	}
+	if exif != nil {
+		exif = exif.withOrder(d.order)
+		for _, e := range exif.ifd0 {
+			// Fields written by the encoder take precedence.
+			if _, ok := d.ifd0.find(e.tag); !ok {
+				d.ifd0 = append(d.ifd0, e)
+			}
+		}
+		d.exif, d.gps, d.interop = exif.exif, exif.gps, exif.interop
+	}
+	if xmp != nil {
+		d.ifd0 = d.ifd0.set(exifEntry{tagXMP, exifByte, uint32(len(xmp)), xmp})
+	}
+	if icc != nil {
+		d.ifd0 = d.ifd0.set(exifEntry{tagICCProfile, exifUndefined, uint32(len(icc)), icc})
+	}
+	return rewriteTIFF(b, d), nil
+}
+
+// rewriteTIFF returns the TIFF file b with its IFDs replaced by those of d, which
+// was parsed from b. The IFDs written after the image data by the encoders are
+// dropped, so that the file holds no unreferenced IFDs.
+func rewriteTIFF(b []byte, d *exifData) []byte {
+	end, found := 8, false
+	for _, tags := range [][2]uint16{{0x0111, 0x0117}, {0x0144, 0x0145}} { // strips and tiles
+		offsets, ok1 := d.ifd0.find(tags[0])
+		counts, ok2 := d.ifd0.find(tags[1])
+		if !ok1 || !ok2 {
+			continue
+		}
+		for i := range int(offsets.count) {
+			offset, ok1 := d.uint(offsets, i)
+			count, ok2 := d.uint(counts, i)
+			if !ok1 || !ok2 {
+				return d.appendTo(b)
+			}
+			end, found = max(end, int(offset)+int(count)), true
+		}
+	}
+	if !found || end > len(b) {
+		return d.appendTo(b)
+	}
+	return d.appendTo(b[:end])
+}
+
+type webpChunk struct {
+	fourCC string
+	data   []byte
+}
+
+func appendWebPChunk(b []byte, c webpChunk) []byte {
+	b = append(b, c.fourCC...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(c.data)))
+	b = append(b, c.data...)
+	if len(c.data)%2 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// embedWebP rewrites the WebP file in the extended format with the ICCP, EXIF and XMP chunks.
+func embedWebP(b []byte, exif *exifData, xmp, icc []byte) ([]byte, error) {
+	errInvalid := errors.New("webp: invalid format")
+	if len(b) < 12 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return nil, errInvalid
+	}
+	var chunks []webpChunk
+	for p := b[12:]; len(p) > 0; {
+		if len(p) < 8 {
+			return nil, errInvalid
+		}
+		size := uint64(binary.LittleEndian.Uint32(p[4:]))
+		if 8+size > uint64(len(p)) {
+			return nil, errInvalid
+		}
+		chunks = append(chunks, webpChunk{string(p[:4]), p[8 : 8+size]})
+		p = p[min(uint64(len(p)), 8+size+size&1):]
+	}
+	if len(chunks) == 0 {
+		return nil, errInvalid
+	}
+
+	// Determine the canvas size and flags.
+	var flags byte
+	var width, height uint32
+	switch c := chunks[0]; c.fourCC {
+	case "VP8X":
+		if len(c.data) < 10 {
+			return nil, errInvalid
+		}
+		flags = c.data[0]
+		width = (uint32(c.data[4]) | uint32(c.data[5])<<8 | uint32(c.data[6])<<16) + 1
+		height = (uint32(c.data[7]) | uint32(c.data[8])<<8 | uint32(c.data[9])<<16) + 1
+	case "VP8L":
+		if len(c.data) < 5 || c.data[0] != 0x2f {
+			return nil, errInvalid
+		}
+		bits := binary.LittleEndian.Uint32(c.data[1:])
+		width, height = bits&0x3fff+1, bits>>14&0x3fff+1
+		if bits>>28&1 != 0 {
+			flags |= webpFlagAlpha
+		}
+	case "VP8 ":
+		if len(c.data) < 10 || !bytes.Equal(c.data[3:6], []byte{0x9d, 0x01, 0x2a}) {
+			return nil, errInvalid
+		}
+		width = uint32(binary.LittleEndian.Uint16(c.data[6:]) & 0x3fff)
+		height = uint32(binary.LittleEndian.Uint16(c.data[8:]) & 0x3fff)
+	default:
+		return nil, errInvalid
+	}
+
+	var body []webpChunk
+	for _, c := range chunks {
+		switch c.fourCC {
+		case "VP8X", "ICCP", "EXIF", "XMP ":
+		default:
+			body = append(body, c)
+		}
+	}
+	flags &^= webpFlagICC | webpFlagEXIF | webpFlagXMP
+	if icc != nil {
+		flags |= webpFlagICC
+		body = append([]webpChunk{{"ICCP", icc}}, body...)
+	}
+	if exif != nil {
+		flags |= webpFlagEXIF
+		body = append(body, webpChunk{"EXIF", exif.encode()})
+	}
+	if xmp != nil {
+		flags |= webpFlagXMP
+		body = append(body, webpChunk{"XMP ", xmp})
+	}
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = flags
+	for i, v := range []uint32{width - 1, height - 1} {
+		vp8x[4+i*3], vp8x[5+i*3], vp8x[6+i*3] = byte(v), byte(v>>8), byte(v>>16)
+	}
+	res := appendWebPChunk([]byte("RIFF\x00\x00\x00\x00WEBP"), webpChunk{"VP8X", vp8x})
+	for _, c := range body {
+		res = appendWebPChunk(res, c)
+	}
+	binary.LittleEndian.PutUint32(res[4:], uint32(len(res)-8))
+	return res, nil
+}
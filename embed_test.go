@@ -0,0 +1,128 @@
+package imgconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+func TestEmbedMetadata(t *testing.T) {
+	x, err := newEXIF(sampleExif(binary.BigEndian))
+	if err != nil {
+		t.Fatal(err)
+	}
+	icc := bytes.Repeat([]byte("icc"), 30000)
+	copy(icc[16:], "RGB ")
+	md := &Metadata{EXIF: x, XMP: []byte("<x:xmpmeta/>"), ICC: icc}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(1, 1, color.NRGBA{0xff, 0, 0, 0x80})
+	for _, format := range []Format{JPEG, PNG, TIFF, WEBP} {
+		var buf bytes.Buffer
+		if err := (&FormatOption{format, []EncodeOption{EmbedMetadata(md)}}).Encode(&buf, img); err != nil {
+			t.Fatal(format, err)
+		}
+
+		res, err := ReadMetadata(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(format, err)
+		}
+		if res.EXIF == nil || res.EXIF.Model != x.Model || res.EXIF.GPS == nil || *res.EXIF.GPS != *x.GPS {
+			t.Errorf("%s: wrong EXIF: %+v", format, res.EXIF)
+		} else if res.EXIF.Width != 3 || res.EXIF.Height != 2 || res.EXIF.Orientation != 6 {
+			t.Errorf("%s: wrong dimensions or orientation: %d %d %d", format, res.EXIF.Width, res.EXIF.Height, res.EXIF.Orientation)
+		}
+		if !bytes.Equal(res.XMP, md.XMP) {
+			t.Errorf("%s: wrong XMP: %q", format, res.XMP)
+		}
+		if !bytes.Equal(res.ICC, md.ICC) {
+			t.Errorf("%s: wrong ICC profile of %d bytes", format, len(res.ICC))
+		}
+
+		dst, err := Decode(&buf, AutoOrientation(false))
+		if err != nil {
+			t.Fatal(format, err)
+		}
+		if dst.Bounds().Size() != img.Bounds().Size() {
+			t.Errorf("%s: bounds differ: %v and %v", format, dst.Bounds(), img.Bounds())
+		}
+	}
+
+	// Blocks too large for a JPEG segment are left out.
+	var buf bytes.Buffer
+	large := &Metadata{EXIF: x, XMP: bytes.Repeat([]byte("x"), 0x10000)}
+	if err := (&FormatOption{JPEG, []EncodeOption{EmbedMetadata(large)}}).Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := ReadMetadata(&buf); err != nil {
+		t.Fatal(err)
+	} else if res.EXIF == nil || res.XMP != nil {
+		t.Errorf("want EXIF only, got %+v", res)
+	}
+
+	// Profiles of another color space than the encoded image are dropped.
+	grayICC := slices.Clone(icc)
+	copy(grayICC[16:], "GRAY")
+	gray := image.NewGray(image.Rect(0, 0, 3, 2))
+	for _, format := range []Format{JPEG, PNG, TIFF} {
+		for _, tc := range []struct {
+			img  image.Image
+			icc  []byte
+			want bool
+		}{
+			{gray, icc, false},
+			{gray, grayICC, true},
+			{img, grayICC, false},
+		} {
+			var buf bytes.Buffer
+			if err := (&FormatOption{format, []EncodeOption{EmbedMetadata(&Metadata{ICC: tc.icc})}}).Encode(&buf, tc.img); err != nil {
+				t.Fatal(format, err)
+			}
+			res, err := ReadMetadata(&buf)
+			if err != nil {
+				t.Fatal(format, err)
+			}
+			if got := res.ICC != nil; got != tc.want {
+				t.Errorf("%s: %T with %q profile: want profile %t, got %t", format, tc.img, tc.icc[16:20], tc.want, got)
+			}
+		}
+	}
+}
+
+func TestExtractMetadata(t *testing.T) {
+	data := pngWithExif(t, image.NewGray(image.Rect(0, 0, 2, 1)), sampleExif(binary.LittleEndian))
+
+	var md Metadata
+	img, err := Decode(bytes.NewReader(data), ExtractMetadata(&md))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Size() != image.Pt(1, 2) {
+		t.Errorf("want auto-oriented size (1,2), got %v", img.Bounds().Size())
+	}
+	if md.EXIF == nil || md.EXIF.Orientation != orientationNormal {
+		t.Fatalf("want orientation reset to normal, got %+v", md.EXIF)
+	}
+
+	var buf bytes.Buffer
+	if err := NewOptions().SetMetadata(EXIFMetadata).ConvertWithMetadata(&buf, img, &md); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ReadMetadata(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EXIF == nil || res.EXIF.Orientation != orientationNormal || res.EXIF.Make != "Canon" {
+		t.Errorf("wrong EXIF: %+v", res.EXIF)
+	}
+
+	if _, err := Decode(bytes.NewReader(data), AutoOrientation(false), ExtractMetadata(&md)); err != nil {
+		t.Fatal(err)
+	}
+	if md.EXIF == nil || md.EXIF.Orientation != orientationRotate270 {
+		t.Errorf("want orientation kept, got %+v", md.EXIF)
+	}
+}
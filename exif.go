@@ -0,0 +1,431 @@
+package imgconv
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+var errInvalidExif = errors.New("exif: invalid format")
+
+// TIFF field types.
+const (
+	exifByte      = 1
+	exifASCII     = 2
+	exifShort     = 3
+	exifLong      = 4
+	exifRational  = 5
+	exifSByte     = 6
+	exifUndefined = 7
+	exifSShort    = 8
+	exifSLong     = 9
+	exifSRational = 10
+	exifFloat     = 11
+	exifDouble    = 12
+)
+
+var exifTypeSize = [...]uint64{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+// EXIF tags.
+const (
+	tagImageWidth            = 0x0100
+	tagImageLength           = 0x0101
+	tagMake                  = 0x010f
+	tagModel                 = 0x0110
+	tagOrientation           = 0x0112
+	tagXResolution           = 0x011a
+	tagYResolution           = 0x011b
+	tagResolutionUnit        = 0x0128
+	tagSoftware              = 0x0131
+	tagDateTime              = 0x0132
+	tagXMP                   = 0x02bc
+	tagExifIFD               = 0x8769
+	tagGPSIFD                = 0x8825
+	tagExposureTime          = 0x829a
+	tagFNumber               = 0x829d
+	tagISOSpeed              = 0x8827
+	tagDateTimeOriginal      = 0x9003
+	tagOffsetTimeOriginal    = 0x9011
+	tagFocalLength           = 0x920a
+	tagICCProfile            = 0x8773
+	tagInteropIFD            = 0xa005
+	tagPixelXDimension       = 0xa002
+	tagPixelYDimension       = 0xa003
+	tagFocalLengthIn35mmFilm = 0xa405
+	tagLensMake              = 0xa433
+	tagLensModel             = 0xa434
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+)
+
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// exifEntry is a TIFF field. The value holds the raw field data in the byte order
+// of the EXIF block it was read from.
+type exifEntry struct {
+	tag, typ uint16
+	count    uint32
+	value    []byte
+}
+
+type exifIFD []exifEntry
+
+// set returns ifd with e replacing the field of the same tag, or appended if not present.
+func (ifd exifIFD) set(e exifEntry) exifIFD {
+	ifd = slices.Clone(ifd)
+	if i := slices.IndexFunc(ifd, func(f exifEntry) bool { return f.tag == e.tag }); i >= 0 {
+		ifd[i] = e
+		return ifd
+	}
+	return append(ifd, e)
+}
+
+// delete returns ifd without the fields of the given tags.
+func (ifd exifIFD) delete(tags ...uint16) exifIFD {
+	return slices.DeleteFunc(slices.Clone(ifd), func(e exifEntry) bool { return slices.Contains(tags, e.tag) })
+}
+
+func (ifd exifIFD) find(tag uint16) (exifEntry, bool) {
+	if i := slices.IndexFunc(ifd, func(e exifEntry) bool { return e.tag == tag }); i >= 0 {
+		return ifd[i], true
+	}
+	return exifEntry{}, false
+}
+
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// exifData is a parsed EXIF block. Pointer tags to the sub-IFDs are not kept in
+// the IFD entries, the sub-IFDs are stored separately instead.
+type exifData struct {
+	order   byteOrder
+	ifd0    exifIFD
+	exif    exifIFD
+	gps     exifIFD
+	interop exifIFD
+}
+
+// parseExif parses the TIFF structured EXIF data in b.
+func parseExif(b []byte) (*exifData, error) {
+	if len(b) < 8 {
+		return nil, errInvalidExif
+	}
+	d := new(exifData)
+	switch {
+	case bytes.HasPrefix(b, tiffMagicLE):
+		d.order = binary.LittleEndian
+	case bytes.HasPrefix(b, tiffMagicBE):
+		d.order = binary.BigEndian
+	default:
+		return nil, errInvalidExif
+	}
+
+	var err error
+	if d.ifd0, err = d.readIFD(b, uint64(d.order.Uint32(b[4:]))); err != nil {
+		return nil, err
+	}
+	if d.ifd0, d.exif, err = d.readSubIFD(b, d.ifd0, tagExifIFD); err != nil {
+		return nil, err
+	}
+	if d.ifd0, d.gps, err = d.readSubIFD(b, d.ifd0, tagGPSIFD); err != nil {
+		return nil, err
+	}
+	if d.exif, d.interop, err = d.readSubIFD(b, d.exif, tagInteropIFD); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// readIFD reads the IFD at offset in b. Fields of unknown types are skipped.
+func (d *exifData) readIFD(b []byte, offset uint64) (ifd exifIFD, err error) {
+	if offset < 8 || offset+2 > uint64(len(b)) {
+		return nil, errInvalidExif
+	}
+	n := uint64(d.order.Uint16(b[offset:]))
+	p := offset + 2
+	if p+n*12 > uint64(len(b)) {
+		return nil, errInvalidExif
+	}
+	for ; n > 0; n, p = n-1, p+12 {
+		e := exifEntry{
+			tag:   d.order.Uint16(b[p:]),
+			typ:   d.order.Uint16(b[p+2:]),
+			count: d.order.Uint32(b[p+4:]),
+		}
+		if e.typ == 0 || int(e.typ) >= len(exifTypeSize) {
+			continue
+		}
+		size := exifTypeSize[e.typ] * uint64(e.count)
+		value := p + 8
+		if size > 4 {
+			value = uint64(d.order.Uint32(b[p+8:]))
+		}
+		if value+size > uint64(len(b)) {
+			return nil, errInvalidExif
+		}
+		e.value = slices.Clone(b[value : value+size])
+		ifd = append(ifd, e)
+	}
+	return
+}
+
+// readSubIFD reads the sub-IFD referenced by the pointer tag in ifd and returns ifd
+// without the pointer tag.
+func (d *exifData) readSubIFD(b []byte, ifd exifIFD, tag uint16) (exifIFD, exifIFD, error) {
+	e, ok := ifd.find(tag)
+	if !ok {
+		return ifd, nil, nil
+	}
+	ifd = slices.DeleteFunc(ifd, func(e exifEntry) bool { return e.tag == tag })
+	offset, ok := d.uint(e, 0)
+	if !ok {
+		return ifd, nil, nil
+	}
+	sub, err := d.readIFD(b, uint64(offset))
+	return ifd, sub, err
+}
+
+// uint returns the i-th value of an unsigned integer field.
+func (d *exifData) uint(e exifEntry, i int) (uint32, bool) {
+	if uint64(i) >= uint64(e.count) {
+		return 0, false
+	}
+	switch e.typ {
+	case exifByte, exifUndefined:
+		return uint32(e.value[i]), true
+	case exifShort:
+		return uint32(d.order.Uint16(e.value[i*2:])), true
+	case exifLong:
+		return d.order.Uint32(e.value[i*4:]), true
+	}
+	return 0, false
+}
+
+// float returns the i-th value of a rational or floating point field.
+func (d *exifData) float(e exifEntry, i int) (float64, bool) {
+	if uint64(i) >= uint64(e.count) {
+		return 0, false
+	}
+	switch e.typ {
+	case exifRational:
+		num, den := d.order.Uint32(e.value[i*8:]), d.order.Uint32(e.value[i*8+4:])
+		if den == 0 {
+			return 0, false
+		}
+		return float64(num) / float64(den), true
+	case exifSRational:
+		num, den := int32(d.order.Uint32(e.value[i*8:])), int32(d.order.Uint32(e.value[i*8+4:]))
+		if den == 0 {
+			return 0, false
+		}
+		return float64(num) / float64(den), true
+	case exifFloat:
+		return float64(math.Float32frombits(d.order.Uint32(e.value[i*4:]))), true
+	case exifDouble:
+		return math.Float64frombits(d.order.Uint64(e.value[i*8:])), true
+	}
+	if v, ok := d.uint(e, i); ok {
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// string returns the value of an ASCII field.
+func (d *exifData) string(e exifEntry) string {
+	if e.typ != exifASCII && e.typ != exifUndefined {
+		return ""
+	}
+	s, _, _ := bytes.Cut(e.value, []byte{0})
+	return strings.TrimSpace(string(s))
+}
+
+func (d *exifData) lookupUint(ifd exifIFD, tag uint16) (uint32, bool) {
+	if e, ok := ifd.find(tag); ok {
+		return d.uint(e, 0)
+	}
+	return 0, false
+}
+
+func (d *exifData) lookupFloat(ifd exifIFD, tag uint16) (float64, bool) {
+	if e, ok := ifd.find(tag); ok {
+		return d.float(e, 0)
+	}
+	return 0, false
+}
+
+func (d *exifData) lookupString(ifd exifIFD, tag uint16) string {
+	if e, ok := ifd.find(tag); ok {
+		return d.string(e)
+	}
+	return ""
+}
+
+// lookupTime returns the time of a date time field, using the location described
+// by the offset field if present or the local time zone otherwise.
+func (d *exifData) lookupTime(ifd exifIFD, tag, offsetTag uint16) time.Time {
+	s := d.lookupString(ifd, tag)
+	if s == "" {
+		return time.Time{}
+	}
+	loc := time.Local
+	if offset := d.lookupString(ifd, offsetTag); offset != "" {
+		if t, err := time.Parse("-07:00", offset); err == nil {
+			loc = t.Location()
+		}
+	}
+	t, err := time.ParseInLocation(exifTimeLayout, s, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// lookupCoordinate returns a GPS coordinate in decimal degrees.
+func (d *exifData) lookupCoordinate(tag, refTag uint16, negative string) (float64, bool) {
+	e, ok := d.gps.find(tag)
+	if !ok {
+		return 0, false
+	}
+	var v float64
+	for i, unit := range []float64{1, 60, 3600} {
+		f, ok := d.float(e, i)
+		if !ok {
+			return 0, false
+		}
+		v += f / unit
+	}
+	if strings.EqualFold(d.lookupString(d.gps, refTag), negative) {
+		v = -v
+	}
+	return v, true
+}
+
+// tiffStructureTags are the IFD0 fields describing the layout of TIFF image data.
+// They are dropped when EXIF metadata read from a TIFF file is written to another image.
+var tiffStructureTags = []uint16{
+	0x00fe, 0x00ff, // NewSubfileType, SubfileType
+	0x0100, 0x0101, 0x0102, 0x0103, // ImageWidth, ImageLength, BitsPerSample, Compression
+	0x0106, 0x0107, 0x010a, // PhotometricInterpretation, Threshholding, FillOrder
+	0x0111, 0x0115, 0x0116, 0x0117, // StripOffsets, SamplesPerPixel, RowsPerStrip, StripByteCounts
+	0x0118, 0x0119, 0x011c, // MinSampleValue, MaxSampleValue, PlanarConfiguration
+	0x013d, 0x0140, // Predictor, ColorMap
+	0x0142, 0x0143, 0x0144, 0x0145, // TileWidth, TileLength, TileOffsets, TileByteCounts
+	0x014a, 0x0152, 0x0153, // SubIFDs, ExtraSamples, SampleFormat
+	0x0201, 0x0202, // JPEGInterchangeFormat, JPEGInterchangeFormatLength
+	0x0211, 0x0212, // YCbCrCoefficients, YCbCrSubSampling
+	tagXMP, tagICCProfile,
+}
+
+// reorder returns e with its value converted from one byte order to another.
+func (e exifEntry) reorder(from, to binary.ByteOrder) exifEntry {
+	if from == to {
+		return e
+	}
+	var size int
+	switch e.typ {
+	case exifShort, exifSShort:
+		size = 2
+	case exifLong, exifSLong, exifFloat, exifRational, exifSRational:
+		size = 4
+	case exifDouble:
+		size = 8
+	default:
+		return e
+	}
+	e.value = slices.Clone(e.value)
+	for i := 0; i+size <= len(e.value); i += size {
+		slices.Reverse(e.value[i : i+size])
+	}
+	return e
+}
+
+// withOrder returns a copy of d using the given byte order.
+func (d *exifData) withOrder(order byteOrder) *exifData {
+	reorder := func(ifd exifIFD) (res exifIFD) {
+		for _, e := range ifd {
+			res = append(res, e.reorder(d.order, order))
+		}
+		return
+	}
+	return &exifData{
+		order:   order,
+		ifd0:    reorder(d.ifd0),
+		exif:    reorder(d.exif),
+		gps:     reorder(d.gps),
+		interop: reorder(d.interop),
+	}
+}
+
+// encode returns the TIFF structure of d.
+func (d *exifData) encode() []byte {
+	var b []byte
+	if d.order == binary.LittleEndian {
+		b = append(b, tiffMagicLE...)
+	} else {
+		b = append(b, tiffMagicBE...)
+	}
+	return d.appendTo(d.order.AppendUint32(b, 0))
+}
+
+// appendTo appends the IFDs of d to the TIFF structure in b and points the header
+// of b to the appended IFD0. Any data already in b is left in place.
+func (d *exifData) appendTo(b []byte) []byte {
+	var offset uint32
+	ifd0, exif := d.ifd0, d.exif
+	if len(d.interop) > 0 {
+		b, offset = d.appendIFD(b, d.interop)
+		exif = exif.set(exifEntry{tagInteropIFD, exifLong, 1, d.order.AppendUint32(nil, offset)})
+	}
+	if len(exif) > 0 {
+		b, offset = d.appendIFD(b, exif)
+		ifd0 = ifd0.set(exifEntry{tagExifIFD, exifLong, 1, d.order.AppendUint32(nil, offset)})
+	}
+	if len(d.gps) > 0 {
+		b, offset = d.appendIFD(b, d.gps)
+		ifd0 = ifd0.set(exifEntry{tagGPSIFD, exifLong, 1, d.order.AppendUint32(nil, offset)})
+	}
+	b, offset = d.appendIFD(b, ifd0)
+	d.order.PutUint32(b[4:], offset)
+	return b
+}
+
+// appendIFD appends ifd followed by its field values to b and returns the offset of the IFD.
+func (d *exifData) appendIFD(b []byte, ifd exifIFD) ([]byte, uint32) {
+	if len(b)%2 != 0 {
+		b = append(b, 0) // IFDs begin on a word boundary.
+	}
+	ifd = slices.SortedStableFunc(slices.Values(ifd), func(a, b exifEntry) int { return cmp.Compare(a.tag, b.tag) })
+	offset := len(b)
+	next := offset + 2 + len(ifd)*12 + 4
+	b = d.order.AppendUint16(b, uint16(len(ifd)))
+	var data []byte
+	for _, e := range ifd {
+		b = d.order.AppendUint16(b, e.tag)
+		b = d.order.AppendUint16(b, e.typ)
+		b = d.order.AppendUint32(b, e.count)
+		if len(e.value) <= 4 {
+			b = append(b, e.value...)
+			b = append(b, make([]byte, 4-len(e.value))...)
+			continue
+		}
+		b = d.order.AppendUint32(b, uint32(next+len(data)))
+		data = append(data, e.value...)
+		if len(data)%2 != 0 {
+			data = append(data, 0)
+		}
+	}
+	b = d.order.AppendUint32(b, 0)
+	return append(b, data...), uint32(offset)
+}
@@ -0,0 +1,96 @@
+package imgconv
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func asciiEntry(tag uint16, s string) exifEntry {
+	return exifEntry{tag, exifASCII, uint32(len(s) + 1), append([]byte(s), 0)}
+}
+
+func shortEntry(order byteOrder, tag uint16, v ...uint16) exifEntry {
+	var b []byte
+	for _, v := range v {
+		b = order.AppendUint16(b, v)
+	}
+	return exifEntry{tag, exifShort, uint32(len(v)), b}
+}
+
+func rationalEntry(order byteOrder, tag uint16, v ...uint32) exifEntry {
+	var b []byte
+	for _, v := range v {
+		b = order.AppendUint32(b, v)
+	}
+	return exifEntry{tag, exifRational, uint32(len(v) / 2), b}
+}
+
+// buildExif returns the TIFF structure of the given IFDs.
+func buildExif(order byteOrder, ifd0, exif, gps exifIFD) []byte {
+	return (&exifData{order: order, ifd0: ifd0, exif: exif, gps: gps}).encode()
+}
+
+func sampleExif(order byteOrder) []byte {
+	return buildExif(
+		order,
+		exifIFD{
+			asciiEntry(tagMake, "Canon"),
+			asciiEntry(tagModel, "Canon EOS R5"),
+			shortEntry(order, tagOrientation, 6),
+		},
+		exifIFD{
+			rationalEntry(order, tagExposureTime, 1, 250),
+			rationalEntry(order, tagFNumber, 28, 10),
+			shortEntry(order, tagISOSpeed, 400),
+			asciiEntry(tagDateTimeOriginal, "2024:05:06 07:08:09"),
+			asciiEntry(tagOffsetTimeOriginal, "+08:00"),
+			rationalEntry(order, tagFocalLength, 50, 1),
+			shortEntry(order, tagPixelXDimension, 8192),
+			shortEntry(order, tagPixelYDimension, 5464),
+			asciiEntry(tagLensModel, "RF24-70mm F2.8 L IS USM"),
+		},
+		exifIFD{
+			asciiEntry(tagGPSLatitudeRef, "N"),
+			rationalEntry(order, tagGPSLatitude, 31, 1, 12, 1, 36, 1),
+			asciiEntry(tagGPSLongitudeRef, "W"),
+			rationalEntry(order, tagGPSLongitude, 121, 1, 30, 1, 0, 1),
+			{tagGPSAltitudeRef, exifByte, 1, []byte{1}},
+			rationalEntry(order, tagGPSAltitude, 15, 2),
+		},
+	)
+}
+
+func TestParseExif(t *testing.T) {
+	for _, order := range []byteOrder{binary.LittleEndian, binary.BigEndian} {
+		x, err := newEXIF(sampleExif(order))
+		if err != nil {
+			t.Fatal(order, err)
+		}
+		if x.Make != "Canon" || x.Model != "Canon EOS R5" || x.LensModel != "RF24-70mm F2.8 L IS USM" {
+			t.Errorf("%s: wrong camera or lens: %q %q %q", order, x.Make, x.Model, x.LensModel)
+		}
+		if x.ExposureTime != 1.0/250 || x.FNumber != 2.8 || x.ISO != 400 || x.FocalLength != 50 {
+			t.Errorf("%s: wrong exposure: %v %v %v %v", order, x.ExposureTime, x.FNumber, x.ISO, x.FocalLength)
+		}
+		if want := time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("", 8*3600)); !x.DateTimeOriginal.Equal(want) {
+			t.Errorf("%s: want DateTimeOriginal %v, got %v", order, want, x.DateTimeOriginal)
+		}
+		if x.Width != 8192 || x.Height != 5464 || x.Orientation != 6 {
+			t.Errorf("%s: wrong dimensions or orientation: %d %d %d", order, x.Width, x.Height, x.Orientation)
+		}
+		if want := (GPS{31.21, -121.5, -7.5}); x.GPS == nil || *x.GPS != want {
+			t.Errorf("%s: want GPS %v, got %v", order, want, x.GPS)
+		}
+	}
+
+	for i, b := range [][]byte{
+		nil,
+		[]byte("II*\x00\xff\x00\x00\x00"),
+		[]byte("MM\x00*\x00\x00\x00\x08\x00\x01"),
+	} {
+		if _, err := parseExif(b); err == nil {
+			t.Errorf("#%d want error, got nil", i)
+		}
+	}
+}
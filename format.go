@@ -1,6 +1,7 @@
 package imgconv
 
 import (
+	"bytes"
 	"encoding"
 	"fmt"
 	"image"
@@ -142,6 +143,8 @@ type encodeConfig struct {
 	webpUseExtendedFormat bool
 	webpCompressionLevel  nativewebp.CompressionLevel
 	background            color.Color
+	metadata              *Metadata
+	dpi                   float64
 }
 
 var defaultEncodeConfig = encodeConfig{
@@ -229,6 +232,23 @@ func BackgroundColor(color color.Color) EncodeOption {
 	}
 }
 
+// DPI returns an EncodeOption that sets the physical resolution in dots per inch
+// of the JPEG, PNG, TIFF or BMP-encoded image. For other formats, it is only written
+// to the embedded EXIF metadata.
+func DPI(dpi float64) EncodeOption {
+	return func(c *encodeConfig) {
+		c.dpi = dpi
+	}
+}
+
+// EmbedMetadata returns an EncodeOption that writes the metadata md into the
+// JPEG, PNG, TIFF or WEBP-encoded image. Other formats ignore the metadata.
+func EmbedMetadata(md *Metadata) EncodeOption {
+	return func(c *encodeConfig) {
+		c.metadata = md
+	}
+}
+
 // Encode writes the image img to w in the specified format (JPEG, PNG, GIF, TIFF, BMP, PDF or WEBP).
 func (f *FormatOption) Encode(w io.Writer, img image.Image) error {
 	cfg := defaultEncodeConfig
@@ -236,14 +256,39 @@ func (f *FormatOption) Encode(w io.Writer, img image.Image) error {
 		option(&cfg)
 	}
 
-	if cfg.background != nil {
+	if _, _, ok := isBilevel(img); cfg.background != nil && !ok {
 		i := image.NewNRGBA(img.Bounds())
 		draw.Draw(i, i.Bounds(), &image.Uniform{cfg.background}, img.Bounds().Min, draw.Src)
 		draw.Draw(i, i.Bounds(), img, img.Bounds().Min, draw.Over)
 		img = i
 	}
 
-	switch f.Format {
+	if cfg.metadata == nil && cfg.dpi <= 0 {
+		return encode(w, img, f.Format, &cfg)
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, img, f.Format, &cfg); err != nil {
+		return err
+	}
+	b := buf.Bytes()
+	var err error
+	if cfg.metadata != nil {
+		if b, err = cfg.metadata.embed(f.Format, b, img, cfg.dpi); err != nil {
+			return err
+		}
+	}
+	if cfg.dpi > 0 {
+		if b, err = embedDPI(f.Format, b, cfg.dpi); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func encode(w io.Writer, img image.Image, format Format, cfg *encodeConfig) error {
+	switch format {
 	case JPEG:
 		if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Opaque() {
 			rgba := &image.RGBA{
@@ -267,12 +312,20 @@ func (f *FormatOption) Encode(w io.Writer, img image.Image) error {
 		})
 
 	case TIFF:
+		if m, white, ok := isBilevel(img); ok {
+			return encodeBilevelTIFF(w, m, white, cfg.tiffCompressionType)
+		}
 		return tiff.Encode(w, img, &tiff.Options{Compression: cfg.tiffCompressionType.value(), Predictor: true})
 
 	case BMP:
 		return bmp.Encode(w, img)
 
 	case PDF:
+		if m, white, ok := isBilevel(img); ok {
+			// Store 1-bit images losslessly, they compress far better with Flate
+			// than with JPEG.
+			return encodeBilevelPDF(w, m, white)
+		}
 		return pdf.Encode(w, []image.Image{img}, &pdf.Options{Quality: cfg.Quality})
 
 	case WEBP:
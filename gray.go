@@ -1,17 +1,207 @@
 package imgconv
 
-import "image"
+import (
+	"encoding"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
 
+var (
+	_ encoding.TextUnmarshaler = new(GrayMode)
+	_ encoding.TextMarshaler   = GrayMode(0)
+)
+
+// GrayMode defines how the gray level is computed from the color channels.
+type GrayMode int
+
+const (
+	// GrayRec601 uses the luma weights of ITU-R BT.601, as JPEG and the standard
+	// library gray color model do.
+	GrayRec601 GrayMode = iota
+	// GrayRec709 uses the luma weights of ITU-R BT.709, as sRGB and HDTV do.
+	GrayRec709
+	// GrayAverage uses the mean of the red, green and blue channels.
+	GrayAverage
+	// GrayLightness uses the mean of the lowest and highest channels.
+	GrayLightness
+	// GrayRed uses the red channel only.
+	GrayRed
+	// GrayGreen uses the green channel only.
+	GrayGreen
+	// GrayBlue uses the blue channel only.
+	GrayBlue
+)
+
+var grayModes = []string{
+	"rec601",
+	"rec709",
+	"average",
+	"lightness",
+	"red",
+	"green",
+	"blue",
+}
+
+func (m *GrayMode) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	for index, mode := range grayModes {
+		if s == mode {
+			*m = GrayMode(index)
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported gray mode: %s", s)
+}
+
+func (m GrayMode) MarshalText() ([]byte, error) {
+	if m < 0 || int(m) >= len(grayModes) {
+		return []byte("unknown"), nil
+	}
+	return []byte(grayModes[m]), nil
+}
+
+// gray returns the 16-bit gray level of the 16-bit color channels.
+func (m GrayMode) gray(r, g, b uint32) uint32 {
+	switch m {
+	case GrayRec709:
+		return (13933*r + 46871*g + 4732*b + 1<<15) >> 16
+	case GrayAverage:
+		return (r + g + b + 1) / 3
+	case GrayLightness:
+		return (max(r, g, b) + min(r, g, b) + 1) / 2
+	case GrayRed:
+		return r
+	case GrayGreen:
+		return g
+	case GrayBlue:
+		return b
+	}
+	return (19595*r + 38470*g + 7471*b + 1<<15) >> 16
+}
+
+// GrayOption is grayscale conversion option
+type GrayOption struct {
+	Mode GrayMode
+	// Alpha keeps the alpha channel of images which are not opaque. As the
+	// standard library has no gray and alpha image type, the result is then an
+	// *image.NRGBA or *image.NRGBA64 image with equal color channels. Otherwise
+	// transparent pixels are darkened as by the standard library gray color model.
+	Alpha bool
+}
+
+// ToGray converts the image to grayscale with the Rec. 601 luma weights.
 func ToGray(img image.Image) image.Image {
-	if img, ok := img.(*image.Gray); ok {
+	return Grayscale(img, nil)
+}
+
+// Grayscale converts the image to grayscale according to the option, default if
+// nil. The result is an *image.Gray16 image for 16-bit sources and an *image.Gray
+// image otherwise, keeping the bounds of the source. Gray images are returned
+// unchanged.
+func Grayscale(img image.Image, option *GrayOption) image.Image {
+	if option == nil {
+		option = new(GrayOption)
+	}
+	var deep bool
+	switch img.(type) {
+	case *image.Gray, *image.Gray16:
 		return img
+	case *image.RGBA64, *image.NRGBA64:
+		deep = true
 	}
+	alpha := option.Alpha
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		alpha = false
+	}
+
 	bounds := img.Bounds()
-	gray := image.NewGray(bounds)
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			gray.Set(x, y, img.At(x, y))
+	w := bounds.Dx()
+	var dst image.Image
+	var pix []uint8
+	var stride, size int
+	switch {
+	case alpha && deep:
+		m := image.NewNRGBA64(bounds)
+		dst, pix, stride, size = m, m.Pix, m.Stride, 8
+	case alpha:
+		m := image.NewNRGBA(bounds)
+		dst, pix, stride, size = m, m.Pix, m.Stride, 4
+	case deep:
+		m := image.NewGray16(bounds)
+		dst, pix, stride, size = m, m.Pix, m.Stride, 2
+	default:
+		m := image.NewGray(bounds)
+		dst, pix, stride, size = m, m.Pix, m.Stride, 1
+	}
+
+	parallel(bounds.Min.Y, bounds.Max.Y, func(ys <-chan int) {
+		row := make([]uint32, w*4)
+		for y := range ys {
+			loadRow(img, y, row)
+			d := pix[(y-bounds.Min.Y)*stride : (y-bounds.Min.Y)*stride+w*size]
+			for x := range w {
+				r, g, b, a := row[x*4], row[x*4+1], row[x*4+2], row[x*4+3]
+				if alpha && a != 0 && a != 0xffff {
+					r, g, b = (r*0xffff+a/2)/a, (g*0xffff+a/2)/a, (b*0xffff+a/2)/a
+				}
+				v := option.Mode.gray(r, g, b)
+				switch size {
+				case 1:
+					d[x] = uint8(v >> 8)
+				case 2:
+					d[x*2], d[x*2+1] = uint8(v>>8), uint8(v)
+				case 4:
+					d[x*4], d[x*4+1], d[x*4+2], d[x*4+3] = uint8(v>>8), uint8(v>>8), uint8(v>>8), uint8(a>>8)
+				case 8:
+					for c := range 3 {
+						d[x*8+c*2], d[x*8+c*2+1] = uint8(v>>8), uint8(v)
+					}
+					d[x*8+6], d[x*8+7] = uint8(a>>8), uint8(a)
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// loadRow loads the alpha-premultiplied 16-bit red, green, blue and alpha values
+// of the pixels of the row y of the image into row.
+func loadRow(img image.Image, y int, row []uint32) {
+	b := img.Bounds()
+	switch img := img.(type) {
+	case *image.NRGBA:
+		pix := img.Pix[img.PixOffset(b.Min.X, y):]
+		for i := 0; i < len(row); i += 4 {
+			a := uint32(pix[i+3]) * 0x101
+			row[i] = uint32(pix[i]) * a / 0xff
+			row[i+1] = uint32(pix[i+1]) * a / 0xff
+			row[i+2] = uint32(pix[i+2]) * a / 0xff
+			row[i+3] = a
+		}
+	case *image.RGBA:
+		pix := img.Pix[img.PixOffset(b.Min.X, y):]
+		for i := 0; i < len(row); i += 4 {
+			row[i] = uint32(pix[i]) * 0x101
+			row[i+1] = uint32(pix[i+1]) * 0x101
+			row[i+2] = uint32(pix[i+2]) * 0x101
+			row[i+3] = uint32(pix[i+3]) * 0x101
+		}
+	case *image.YCbCr:
+		for x := range len(row) / 4 {
+			yi, ci := img.YOffset(b.Min.X+x, y), img.COffset(b.Min.X+x, y)
+			r, g, bb, _ := color.YCbCr{img.Y[yi], img.Cb[ci], img.Cr[ci]}.RGBA()
+			row[x*4], row[x*4+1], row[x*4+2], row[x*4+3] = r, g, bb, 0xffff
+		}
+	case image.RGBA64Image:
+		for x := range len(row) / 4 {
+			c := img.RGBA64At(b.Min.X+x, y)
+			row[x*4], row[x*4+1], row[x*4+2], row[x*4+3] = uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+		}
+	default:
+		for x := range len(row) / 4 {
+			row[x*4], row[x*4+1], row[x*4+2], row[x*4+3] = img.At(b.Min.X+x, y).RGBA()
 		}
 	}
-	return gray
 }
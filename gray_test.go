@@ -2,6 +2,7 @@ package imgconv
 
 import (
 	"image"
+	"image/color"
 	"testing"
 )
 
@@ -19,3 +20,98 @@ func TestGray(t *testing.T) {
 		t.Fatal("img is not gray")
 	}
 }
+
+func TestGrayModel(t *testing.T) {
+	// The result matches the standard library gray color model.
+	nrgba := image.NewNRGBA(image.Rect(1, 2, 17, 18))
+	for i := range nrgba.Pix {
+		nrgba.Pix[i] = uint8(i * 37)
+	}
+	rgba := image.NewRGBA(nrgba.Rect)
+	for i := range rgba.Pix {
+		rgba.Pix[i] = uint8(i * 13)
+	}
+	cmyk := image.NewCMYK(image.Rect(0, 0, 4, 4))
+	for i := range cmyk.Pix {
+		cmyk.Pix[i] = uint8(i * 11)
+	}
+	paletted := image.NewPaletted(image.Rect(0, 0, 2, 1), color.Palette{color.NRGBA{10, 200, 30, 128}, color.White})
+	paletted.Pix[1] = 1
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, 8, 8), image.YCbCrSubsampleRatio420)
+	for i := range ycbcr.Y {
+		ycbcr.Y[i] = uint8(40 + i*2)
+	}
+	for i := range ycbcr.Cb {
+		ycbcr.Cb[i], ycbcr.Cr[i] = uint8(120+i), uint8(136-i)
+	}
+	for _, src := range []image.Image{nrgba, rgba, cmyk, paletted, ycbcr, nrgba.SubImage(image.Rect(3, 4, 9, 9))} {
+		img := ToGray(src).(*image.Gray)
+		if img.Bounds() != src.Bounds() {
+			t.Fatalf("%T: want bounds %v, got %v", src, src.Bounds(), img.Bounds())
+		}
+		for y := src.Bounds().Min.Y; y < src.Bounds().Max.Y; y++ {
+			for x := src.Bounds().Min.X; x < src.Bounds().Max.X; x++ {
+				if want := color.GrayModel.Convert(src.At(x, y)); img.At(x, y) != want {
+					t.Fatalf("%T: want %v at (%d, %d), got %v", src, want, x, y, img.At(x, y))
+				}
+			}
+		}
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, 1, 1))
+	if img := ToGray(gray); img != gray {
+		t.Error("want gray image unchanged")
+	}
+}
+
+func TestGrayscale(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	src.Pix = []uint8{200, 100, 50, 255}
+	testCases := []struct {
+		mode GrayMode
+		gray uint8
+	}{
+		{GrayRec601, 124},
+		{GrayRec709, 118},
+		{GrayAverage, 117},
+		{GrayLightness, 125},
+		{GrayRed, 200},
+		{GrayGreen, 100},
+		{GrayBlue, 50},
+	}
+	for _, tc := range testCases {
+		if got := Grayscale(src, &GrayOption{Mode: tc.mode}).(*image.Gray).Pix[0]; got != tc.gray {
+			t.Errorf("%d: want %d, got %d", tc.mode, tc.gray, got)
+		}
+		b, _ := tc.mode.MarshalText()
+		var m GrayMode
+		if err := m.UnmarshalText(b); err != nil || m != tc.mode {
+			t.Errorf("want %d, got %d, %v", tc.mode, m, err)
+		}
+	}
+
+	deep := image.NewNRGBA64(image.Rect(0, 0, 1, 1))
+	deep.SetNRGBA64(0, 0, color.NRGBA64{0x1234, 0x1234, 0x1234, 0xffff})
+	if img, ok := ToGray(deep).(*image.Gray16); !ok {
+		t.Errorf("want *image.Gray16, got %T", ToGray(deep))
+	} else if c := img.Gray16At(0, 0); c.Y != 0x1234 {
+		t.Errorf("want 0x1234, got %#x", c.Y)
+	}
+
+	// Alpha is kept only if asked for.
+	src.Pix = []uint8{200, 100, 50, 128}
+	if c := ToGray(src).(*image.Gray).Pix[0]; c != 62 {
+		t.Errorf("want 62, got %d", c)
+	}
+	if c := Grayscale(src, &GrayOption{Mode: GrayRed, Alpha: true}).(*image.NRGBA).NRGBAAt(0, 0); c != (color.NRGBA{200, 200, 200, 128}) {
+		t.Errorf("want gray with alpha, got %v", c)
+	}
+	deep.SetNRGBA64(0, 0, color.NRGBA64{0x1234, 0, 0, 0x8000})
+	if c := Grayscale(deep, &GrayOption{Mode: GrayRed, Alpha: true}).(*image.NRGBA64).NRGBA64At(0, 0); c.R != 0x1234 || c.B != 0x1234 || c.A != 0x8000 {
+		t.Errorf("want 16-bit gray with alpha, got %v", c)
+	}
+	src.Pix[3] = 255
+	if _, ok := Grayscale(src, &GrayOption{Alpha: true}).(*image.Gray); !ok {
+		t.Error("want *image.Gray for opaque image")
+	}
+}
@@ -0,0 +1,426 @@
+package imgconv
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"sync"
+)
+
+var errInvalidICC = errors.New("icc: invalid profile")
+
+// D50 white point of the profile connection space.
+var iccD50 = [3]float64{0.9642, 1, 0.8249}
+
+// sRGB colorants adapted to D50, as columns of the linear sRGB to XYZ matrix.
+var srgbColorants = [3][3]float64{
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+// XYZ (D50) to linear sRGB matrix.
+var srgbFromXYZ = [3][3]float64{
+	{3.1338561, -1.6168667, -0.4906146},
+	{-0.9787684, 1.9161415, 0.0334540},
+	{0.0719453, -0.2289914, 1.4052427},
+}
+
+// iccProfile holds the transform of an ICC profile from device values to the D50
+// XYZ connection space, either a matrix/TRC transform or a CMYK lookup table.
+type iccProfile struct {
+	gray   bool
+	matrix [3][3]float64
+	trc    [3]func(float64) float64
+	cmyk   *iccLUT
+}
+
+// parseICCProfile parses an RGB or gray matrix/TRC ICC profile, or a CMYK profile
+// with an A2B0 lookup table of lut8Type or lut16Type.
+func parseICCProfile(b []byte) (*iccProfile, error) {
+	if len(b) < 132 || string(b[36:40]) != "acsp" {
+		return nil, errInvalidICC
+	}
+	pcs := string(b[20:24])
+	if pcs != "XYZ " && (pcs != "Lab " || string(b[16:20]) != "CMYK") {
+		return nil, errors.New("icc: unsupported connection space: " + pcs)
+	}
+	count := int(binary.BigEndian.Uint32(b[128:]))
+	if count > (len(b)-132)/12 {
+		return nil, errInvalidICC
+	}
+	tags := make(map[string][]byte, count)
+	for i := range count {
+		entry := b[132+i*12:]
+		offset, size := uint64(binary.BigEndian.Uint32(entry[4:])), uint64(binary.BigEndian.Uint32(entry[8:]))
+		if offset+size > uint64(len(b)) {
+			return nil, errInvalidICC
+		}
+		tags[string(entry[:4])] = b[offset : offset+size]
+	}
+
+	p := new(iccProfile)
+	switch string(b[16:20]) {
+	case "RGB ":
+		for i, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+			xyz, err := parseICCXYZ(tags[sig])
+			if err != nil {
+				return nil, err
+			}
+			for j := range 3 {
+				p.matrix[j][i] = xyz[j]
+			}
+		}
+		for i, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+			trc, err := parseICCCurve(tags[sig])
+			if err != nil {
+				return nil, err
+			}
+			p.trc[i] = trc
+		}
+	case "GRAY":
+		trc, err := parseICCCurve(tags["kTRC"])
+		if err != nil {
+			return nil, err
+		}
+		// Gray values are neutral, they map to the same values in each sRGB channel.
+		p.gray, p.matrix, p.trc = true, srgbColorants, [3]func(float64) float64{trc, trc, trc}
+	case "CMYK":
+		lut, err := parseICCLUT(tags["A2B0"], 4, pcs == "Lab ")
+		if err != nil {
+			return nil, err
+		}
+		p.cmyk = lut
+	default:
+		return nil, errors.New("icc: unsupported color space: " + string(b[16:20]))
+	}
+	return p, nil
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+func parseICCXYZ(b []byte) (xyz [3]float64, err error) {
+	if len(b) < 20 || string(b[:4]) != "XYZ " {
+		return xyz, errInvalidICC
+	}
+	for i := range xyz {
+		xyz[i] = s15Fixed16(b[8+i*4:])
+	}
+	return
+}
+
+// parseICCCurve parses a curveType or parametricCurveType tag into a function
+// mapping encoded values in [0, 1] to linear values.
+func parseICCCurve(b []byte) (func(float64) float64, error) {
+	if len(b) < 12 {
+		return nil, errInvalidICC
+	}
+	switch string(b[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(b[8:]))
+		if len(b) < 12+2*n {
+			return nil, errInvalidICC
+		}
+		switch n {
+		case 0:
+			return func(x float64) float64 { return x }, nil
+		case 1:
+			g := float64(binary.BigEndian.Uint16(b[12:])) / 256
+			return func(x float64) float64 { return math.Pow(x, g) }, nil
+		}
+		table := make([]float64, n)
+		for i := range table {
+			table[i] = float64(binary.BigEndian.Uint16(b[12+2*i:])) / 65535
+		}
+		return func(x float64) float64 {
+			pos := min(max(x, 0), 1) * float64(n-1)
+			i := min(int(pos), n-2)
+			return table[i] + (table[i+1]-table[i])*(pos-float64(i))
+		}, nil
+	case "para":
+		fn := binary.BigEndian.Uint16(b[8:])
+		size := []int{1, 3, 4, 5, 7}
+		if int(fn) >= len(size) || len(b) < 12+4*size[fn] {
+			return nil, errInvalidICC
+		}
+		var v [7]float64
+		for i := range size[fn] {
+			v[i] = s15Fixed16(b[12+4*i:])
+		}
+		g, a, bb, c, d, e, f := v[0], v[1], v[2], v[3], v[4], v[5], v[6]
+		// The power of a negative a*x+b is undefined, and so is the threshold
+		// -b/a if a is 0.
+		if (fn == 1 || fn == 2) && !(a > 0) ||
+			(fn == 3 || fn == 4) && d <= 1 && (a*max(d, 0)+bb < 0 || a+bb < 0) {
+			return nil, errors.New("icc: unsupported parametric curve")
+		}
+		switch fn {
+		case 0:
+			return func(x float64) float64 { return math.Pow(x, g) }, nil
+		case 1:
+			return func(x float64) float64 {
+				if x >= -bb/a {
+					return math.Pow(a*x+bb, g)
+				}
+				return 0
+			}, nil
+		case 2:
+			return func(x float64) float64 {
+				if x >= -bb/a {
+					return math.Pow(a*x+bb, g) + c
+				}
+				return c
+			}, nil
+		case 3:
+			return func(x float64) float64 {
+				if x >= d {
+					return math.Pow(a*x+bb, g)
+				}
+				return c * x
+			}, nil
+		default:
+			return func(x float64) float64 {
+				if x >= d {
+					return math.Pow(a*x+bb, g) + e
+				}
+				return c*x + f
+			}, nil
+		}
+	}
+	return nil, errors.New("icc: unsupported curve type: " + string(b[:4]))
+}
+
+func srgbDecode(x float64) float64 {
+	if x <= 0.04045 {
+		return x / 12.92
+	}
+	return math.Pow((x+0.055)/1.055, 2.4)
+}
+
+func srgbEncode(x float64) float64 {
+	if x <= 0.0031308 {
+		return 12.92 * x
+	}
+	return 1.055*math.Pow(x, 1/2.4) - 0.055
+}
+
+// transform returns the matrix converting linear device values to linear sRGB.
+func (p *iccProfile) transform() (m [3][3]float64) {
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				m[i][j] += srgbFromXYZ[i][k] * p.matrix[k][j]
+			}
+		}
+	}
+	return
+}
+
+// isSRGB reports whether the profile is close enough to sRGB to leave the pixels unchanged.
+func (p *iccProfile) isSRGB() bool {
+	if p.cmyk != nil {
+		return false
+	}
+	m := p.transform()
+	for i := range 3 {
+		for j := range 3 {
+			want := 0.0
+			if i == j {
+				want = 1
+			}
+			if math.Abs(m[i][j]-want) > 2e-3 {
+				return false
+			}
+		}
+	}
+	for _, trc := range p.trc {
+		for v := range 256 {
+			x := float64(v) / 255
+			if math.Abs(trc(x)-srgbDecode(x)) > 1.0/512 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// toSRGB converts the pixels of img from the color space of the profile to sRGB,
+// and reports whether they were converted. 16-bit images are converted to
+// *image.NRGBA64, gray images with a gray profile to *image.Gray, and other images
+// to *image.NRGBA. Images whose color model does not match the profile and images
+// already in sRGB are returned unchanged.
+func (p *iccProfile) toSRGB(img image.Image) (image.Image, bool) {
+	cmyk, ok := img.(*image.CMYK)
+	if p.cmyk != nil {
+		if ok {
+			return p.cmyk.cmykToSRGB(cmyk), true
+		}
+		return img, false
+	}
+	if ok || p.isSRGB() {
+		return img, false
+	}
+
+	depth := 255
+	switch img.(type) {
+	case *image.RGBA64, *image.NRGBA64, *image.Gray16:
+		depth = 0xffff
+	}
+	var lut [3][]float64
+	for c := range lut {
+		lut[c] = make([]float64, depth+1)
+		for v := range lut[c] {
+			lut[c][v] = p.trc[c](float64(v) / float64(depth))
+		}
+	}
+	// enc maps linear values in [0, 1] with 1<<14 steps to sRGB values.
+	enc := make([]uint16, 1<<14+1)
+	for i := range enc {
+		enc[i] = uint16(math.Round(srgbEncode(float64(i)/(1<<14)) * float64(depth)))
+	}
+	m := p.transform()
+	convert := func(r, g, b int) (uint16, uint16, uint16) {
+		lr, lg, lb := lut[0][r], lut[1][g], lut[2][b]
+		var out [3]uint16
+		for i := range out {
+			v := m[i][0]*lr + m[i][1]*lg + m[i][2]*lb
+			if math.IsNaN(v) {
+				v = 0
+			}
+			out[i] = enc[int(min(max(v, 0), 1)*(1<<14)+0.5)]
+		}
+		return out[0], out[1], out[2]
+	}
+
+	if src, ok := img.(*image.Gray); ok && p.gray {
+		dst := image.NewGray(src.Rect.Sub(src.Rect.Min))
+		parallel(0, dst.Rect.Dy(), func(ys <-chan int) {
+			for y := range ys {
+				s := src.Pix[y*src.Stride : y*src.Stride+dst.Rect.Dx()]
+				d := dst.Pix[y*dst.Stride : y*dst.Stride+dst.Rect.Dx()]
+				for x, v := range s {
+					g, _, _ := convert(int(v), int(v), int(v))
+					d[x] = uint8(g)
+				}
+			}
+		})
+		return dst, true
+	}
+
+	if depth == 255 {
+		dst := clone(img)
+		parallel(0, dst.Rect.Dy(), func(ys <-chan int) {
+			for y := range ys {
+				row := dst.Pix[y*dst.Stride : y*dst.Stride+dst.Rect.Dx()*4]
+				for i := 0; i < len(row); i += 4 {
+					r, g, b := convert(int(row[i]), int(row[i+1]), int(row[i+2]))
+					row[i], row[i+1], row[i+2] = uint8(r), uint8(g), uint8(b)
+				}
+			}
+		})
+		return dst, true
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewNRGBA64(bounds.Sub(bounds.Min))
+	parallel(0, dst.Rect.Dy(), func(ys <-chan int) {
+		for y := range ys {
+			for x := range dst.Rect.Dx() {
+				c := color.NRGBA64Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA64)
+				c.R, c.G, c.B = convert(int(c.R), int(c.G), int(c.B))
+				dst.SetNRGBA64(x, y, c)
+			}
+		}
+	})
+	return dst, true
+}
+
+// appendICCTags appends the tag table and the tag data of an ICC profile to its
+// 128 bytes header, and sets the profile size. Identical tag data is shared.
+func appendICCTags(b []byte, sigs []string, data [][]byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(sigs)))
+	offset := len(b) + 12*len(sigs)
+	var body []byte
+	offsets := make(map[string]int)
+	for i, sig := range sigs {
+		o, ok := offsets[string(data[i])]
+		if !ok {
+			o = offset + len(body)
+			offsets[string(data[i])] = o
+			body = append(body, data[i]...)
+			for len(body)%4 != 0 {
+				body = append(body, 0)
+			}
+		}
+		b = append(b, sig...)
+		b = binary.BigEndian.AppendUint32(b, uint32(o))
+		b = binary.BigEndian.AppendUint32(b, uint32(len(data[i])))
+	}
+	b = append(b, body...)
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+func iccXYZ(xyz ...float64) []byte {
+	b := []byte("XYZ \x00\x00\x00\x00")
+	for _, v := range xyz {
+		b = binary.BigEndian.AppendUint32(b, uint32(int32(math.Round(v*65536))))
+	}
+	return b
+}
+
+func iccText(typ, s string) []byte {
+	b := append([]byte(typ), 0, 0, 0, 0)
+	if typ == "desc" {
+		// textDescriptionType with empty Unicode and ScriptCode descriptions.
+		b = binary.BigEndian.AppendUint32(b, uint32(len(s)+1))
+		b = append(b, s...)
+		return append(b, make([]byte, 1+8+3+67)...)
+	}
+	return append(append(b, s...), 0)
+}
+
+// newRGBProfile returns a version 2 display profile with the given D50 colorants
+// (columns of the linear RGB to XYZ matrix) and tone reproduction curve.
+func newRGBProfile(desc string, colorants [3][3]float64, trc []byte) []byte {
+	header := make([]byte, 128)
+	copy(header[8:], []byte{2, 0x10, 0, 0})
+	copy(header[12:], "mntrRGB XYZ ")
+	binary.BigEndian.PutUint16(header[24:], 2000)
+	header[27], header[29] = 1, 1
+	copy(header[36:], "acsp")
+	copy(header[68:], iccXYZ(iccD50[:]...)[8:])
+
+	var sigs []string
+	var data [][]byte
+	add := func(sig string, b []byte) { sigs, data = append(sigs, sig), append(data, b) }
+	add("desc", iccText("desc", desc))
+	add("cprt", iccText("text", "No copyright, use freely"))
+	add("wtpt", iccXYZ(iccD50[:]...))
+	for i, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		add(sig, iccXYZ(colorants[0][i], colorants[1][i], colorants[2][i]))
+	}
+	for _, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		add(sig, trc)
+	}
+	return appendICCTags(header, sigs, data)
+}
+
+var srgbProfile = sync.OnceValue(func() []byte {
+	trc := binary.BigEndian.AppendUint32([]byte("curv\x00\x00\x00\x00"), 1024)
+	for i := range 1024 {
+		trc = binary.BigEndian.AppendUint16(trc, uint16(math.Round(srgbDecode(float64(i)/1023)*0xffff)))
+	}
+	return newRGBProfile("sRGB", srgbColorants, trc)
+})
+
+// SRGBProfile returns an ICC profile describing the sRGB color space. It can be
+// embedded with EmbedMetadata to tag images as sRGB.
+func SRGBProfile() []byte {
+	return slices.Clone(srgbProfile())
+}
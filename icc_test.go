@@ -0,0 +1,182 @@
+package imgconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// adobeRGBProfile returns a profile with the Adobe RGB (1998) primaries and gamma.
+func adobeRGBProfile() []byte {
+	return newRGBProfile("Adobe RGB", [3][3]float64{
+		{0.6097559, 0.2052401, 0.1492240},
+		{0.3111242, 0.6256560, 0.0632197},
+		{0.0194811, 0.0608902, 0.7448387},
+	}, []byte("curv\x00\x00\x00\x00\x00\x00\x00\x01\x02\x33\x00\x00"))
+}
+
+func TestParseICCProfile(t *testing.T) {
+	p, err := parseICCProfile(SRGBProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.isSRGB() {
+		t.Error("want sRGB profile detected")
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	if res, ok := p.toSRGB(img); ok || res != image.Image(img) {
+		t.Error("want image unchanged for sRGB profile")
+	}
+
+	p, err = parseICCProfile(adobeRGBProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.isSRGB() {
+		t.Error("want Adobe RGB profile not detected as sRGB")
+	}
+
+	for _, b := range [][]byte{nil, []byte("Hello"), SRGBProfile()[:200]} {
+		if _, err := parseICCProfile(b); err == nil {
+			t.Errorf("want error for %d bytes profile", len(b))
+		}
+	}
+}
+
+func TestParseICCCurve(t *testing.T) {
+	para := []byte("para\x00\x00\x00\x00\x00\x03\x00\x00")
+	// sRGB parameters: g, a, b, c, d.
+	for _, v := range []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045} {
+		para = binary.BigEndian.AppendUint32(para, uint32(int32(math.Round(v*65536))))
+	}
+	for name, b := range map[string][]byte{
+		"para": para,
+		"curv": srgbProfile()[bytes.Index(srgbProfile(), []byte("curv")):],
+	} {
+		trc, err := parseICCCurve(b)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		for _, x := range []float64{0, 0.02, 0.2, 0.5, 1} {
+			if got, want := trc(x), srgbDecode(x); math.Abs(got-want) > 1e-3 {
+				t.Errorf("%s: want %g at %g, got %g", name, want, x, got)
+			}
+		}
+	}
+	if _, err := parseICCCurve([]byte("mft2\x00\x00\x00\x00\x00\x00\x00\x00")); err == nil {
+		t.Error("want error for unsupported curve type")
+	}
+	if _, err := parseICCCurve([]byte("para\x00\x00\x00\x00\x00\x01\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")); err == nil {
+		t.Error("want error for parametric curve with a = 0")
+	}
+	for _, tc := range []struct {
+		fn     uint16
+		params []float64
+	}{
+		{1, []float64{2.2, -1, 0}},
+		{3, []float64{2.4, 1, -0.5, 1, 0}},
+		{4, []float64{2.4, -1, 0.5, 1, 0.2, 0, 0}},
+	} {
+		b := binary.BigEndian.AppendUint16([]byte("para\x00\x00\x00\x00"), tc.fn)
+		b = append(b, 0, 0)
+		for _, v := range tc.params {
+			b = binary.BigEndian.AppendUint32(b, uint32(int32(math.Round(v*65536))))
+		}
+		if _, err := parseICCCurve(b); err == nil {
+			t.Errorf("want error for parametric curve %d with %v", tc.fn, tc.params)
+		}
+		// The profile is ignored instead of producing undefined values.
+		var buf bytes.Buffer
+		src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		if err := (&FormatOption{PNG, []EncodeOption{EmbedMetadata(&Metadata{ICC: newRGBProfile("bad", srgbColorants, b)})}}).Encode(&buf, src); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Decode(&buf, ConvertToSRGB(true)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Undefined values of the curves are mapped to black.
+	p, err := parseICCProfile(adobeRGBProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for c := range p.trc {
+		p.trc[c] = func(float64) float64 { return math.NaN() }
+	}
+	img, _ := p.toSRGB(whiteImage(2, 1))
+	if c := img.(*image.NRGBA).NRGBAAt(1, 0); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("want black, got %v", c)
+	}
+}
+
+func TestConvertToSRGB(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{128, 128, 128, 255})
+	src.SetNRGBA(1, 0, color.NRGBA{180, 80, 80, 128})
+	var buf bytes.Buffer
+	if err := (&FormatOption{PNG, []EncodeOption{EmbedMetadata(&Metadata{ICC: adobeRGBProfile()})}}).Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	var md Metadata
+	img, err := Decode(bytes.NewReader(buf.Bytes()), ConvertToSRGB(true), ExtractMetadata(&md))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA)
+	if d := int(gray.R) - 128; d < -1 || d > 1 || gray.R != gray.G || gray.G != gray.B {
+		t.Errorf("want neutral gray kept, got %v", gray)
+	}
+	red := color.NRGBAModel.Convert(img.At(1, 0)).(color.NRGBA)
+	if red.R <= 180 || red.G >= 80 || red.A != 128 {
+		t.Errorf("want more saturated red with alpha kept, got %v", red)
+	}
+	if !bytes.Equal(md.ICC, srgbProfile()) {
+		t.Error("want ICC profile replaced with sRGB")
+	}
+
+	img, err = Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.NRGBAModel.Convert(img.At(1, 0)).(color.NRGBA); c != (color.NRGBA{180, 80, 80, 128}) {
+		t.Errorf("want raw values without ConvertToSRGB, got %v", c)
+	}
+
+	src16 := image.NewNRGBA64(image.Rect(0, 0, 1, 1))
+	src16.SetNRGBA64(0, 0, color.NRGBA64{0x8080, 0x8080, 0x8080, 0xffff})
+	p, _ := parseICCProfile(adobeRGBProfile())
+	dst, _ := p.toSRGB(src16)
+	res, ok := dst.(*image.NRGBA64)
+	if !ok {
+		t.Fatalf("want *image.NRGBA64 for 16-bit image, got %T", res)
+	}
+	if c := res.NRGBA64At(0, 0); math.Abs(float64(c.R)-0x8080) > 0x200 || c.R != c.B {
+		t.Errorf("want neutral gray kept, got %v", c)
+	}
+
+	// Profiles which do not match the image are kept along with the pixels.
+	buf.Reset()
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	b, err := embedPNG(buf.Bytes(), nil, nil, cmykProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = Decode(bytes.NewReader(b), ConvertToSRGB(true), ExtractMetadata(&md))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.NRGBAModel.Convert(img.At(1, 0)).(color.NRGBA); c != (color.NRGBA{180, 80, 80, 128}) {
+		t.Errorf("want raw values for a CMYK profile, got %v", c)
+	}
+	if !bytes.Equal(md.ICC, cmykProfile()) {
+		t.Error("want CMYK profile kept")
+	}
+}
@@ -2,7 +2,6 @@
 package imgconv
 
 import (
-	"encoding/binary"
 	"image"
 	"image/color"
 	"io"
@@ -19,15 +18,6 @@ import (
 // DecodeOption sets an optional parameter for the Decode and Open functions.
 type decodeOption func(*decodeConfig)
 
-// AutoOrientation returns a DecodeOption that sets the auto-orientation mode.
-// If auto-orientation is enabled, the image will be transformed after decoding
-// according to the EXIF orientation tag (if present). By default it's disabled.
-func autoOrientation(enabled bool) decodeOption {
-	return func(c *decodeConfig) {
-		c.autoOrientation = enabled
-	}
-}
-
 // Decode reads an image from r.
 func decode(r io.Reader, opts ...decodeOption) (image.Image, error) {
 	cfg := defaultDecodeConfig
@@ -35,28 +25,57 @@ func decode(r io.Reader, opts ...decodeOption) (image.Image, error) {
 		option(&cfg)
 	}
 
-	if !cfg.autoOrientation {
-		img, _, err := image.Decode(r)
-		return img, err
+	if !cfg.autoOrientation && cfg.metadata == nil && !cfg.toSRGB {
+		return decodeImage(r)
 	}
 
 	var orient orientation
+	var md *Metadata
 	pr, pw := io.Pipe()
 	r = io.TeeReader(r, pw)
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		orient = readOrientation(pr)
+		if cfg.metadata != nil || cfg.toSRGB {
+			// Metadata is optional, errors are ignored.
+			if md, _ = ReadMetadata(pr); md != nil && md.EXIF != nil {
+				orient = orientation(md.EXIF.Orientation)
+			}
+		} else {
+			orient = readOrientation(pr)
+		}
 		io.Copy(io.Discard, pr)
 	}()
 
-	img, _, err := image.Decode(r)
+	img, err := decodeImage(r)
 	pw.Close()
 	<-done
 	if err != nil {
 		return nil, err
 	}
 
+	if cfg.toSRGB && md != nil && md.ICC != nil {
+		// Unsupported profiles are ignored.
+		if p, err := parseICCProfile(md.ICC); err == nil {
+			var ok bool
+			if img, ok = p.toSRGB(img); ok {
+				md.ICC = srgbProfile()
+			}
+		}
+	}
+	if cfg.metadata != nil {
+		*cfg.metadata = Metadata{}
+		if md != nil {
+			*cfg.metadata = *md
+		}
+	}
+	if !cfg.autoOrientation {
+		return img, nil
+	}
+	if orient > orientationNormal && cfg.metadata != nil && cfg.metadata.EXIF != nil {
+		cfg.metadata.EXIF.Orientation = orientationNormal
+	}
+
 	return fixOrientation(img, orient), nil
 }
 
@@ -149,6 +168,10 @@ func resize(img image.Image, width, height int, filter resampleFilter) *image.NR
 		return clone(img)
 	}
 
+	if filter.Support <= 0.0 {
+		return resizeNearest(img, dstW, dstH)
+	}
+
 	if srcW != dstW && srcH != dstH {
 		return resizeVertical(resizeHorizontal(img, dstW, filter), dstH, filter)
 	}
@@ -251,6 +274,131 @@ var lanczos = resampleFilter{
 	},
 }
 
+// resizeNearest is a fast nearest-neighbor resize, no filtering used.
+func resizeNearest(img image.Image, width, height int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	dx := float64(img.Bounds().Dx()) / float64(width)
+	dy := float64(img.Bounds().Dy()) / float64(height)
+
+	if dx > 1 && dy > 1 {
+		src := newScanner(img)
+		parallel(0, height, func(ys <-chan int) {
+			for y := range ys {
+				srcY := int((float64(y) + 0.5) * dy)
+				dstOff := y * dst.Stride
+				for x := 0; x < width; x++ {
+					srcX := int((float64(x) + 0.5) * dx)
+					src.scan(srcX, srcY, srcX+1, srcY+1, dst.Pix[dstOff:dstOff+4])
+					dstOff += 4
+				}
+			}
+		})
+	} else {
+		src := toNRGBA(img)
+		parallel(0, height, func(ys <-chan int) {
+			for y := range ys {
+				srcY := int((float64(y) + 0.5) * dy)
+				srcOff0 := srcY * src.Stride
+				dstOff := y * dst.Stride
+				for x := 0; x < width; x++ {
+					srcX := int((float64(x) + 0.5) * dx)
+					srcOff := srcOff0 + srcX*4
+					copy(dst.Pix[dstOff:dstOff+4], src.Pix[srcOff:srcOff+4])
+					dstOff += 4
+				}
+			}
+		})
+	}
+
+	return dst
+}
+
+// nearestNeighbor is a nearest-neighbor filter (no anti-aliasing).
+var nearestNeighbor resampleFilter
+
+// box filter (averaging pixels).
+var box = resampleFilter{
+	Support: 0.5,
+	Kernel: func(x float64) float64 {
+		x = math.Abs(x)
+		if x <= 0.5 {
+			return 1.0
+		}
+		return 0
+	},
+}
+
+// linear filter.
+var linear = resampleFilter{
+	Support: 1.0,
+	Kernel: func(x float64) float64 {
+		x = math.Abs(x)
+		if x < 1.0 {
+			return 1.0 - x
+		}
+		return 0
+	},
+}
+
+// hermite cubic spline filter (BC-spline; B=0; C=0).
+var hermite = resampleFilter{
+	Support: 1.0,
+	Kernel: func(x float64) float64 {
+		x = math.Abs(x)
+		if x < 1.0 {
+			return bcspline(x, 0.0, 0.0)
+		}
+		return 0
+	},
+}
+
+// mitchellNetravali is Mitchell-Netravali cubic filter (BC-spline; B=1/3; C=1/3).
+var mitchellNetravali = resampleFilter{
+	Support: 2.0,
+	Kernel: func(x float64) float64 {
+		x = math.Abs(x)
+		if x < 2.0 {
+			return bcspline(x, 1.0/3.0, 1.0/3.0)
+		}
+		return 0
+	},
+}
+
+// catmullRom is a Catmull-Rom - sharp cubic filter (BC-spline; B=0; C=0.5).
+var catmullRom = resampleFilter{
+	Support: 2.0,
+	Kernel: func(x float64) float64 {
+		x = math.Abs(x)
+		if x < 2.0 {
+			return bcspline(x, 0.0, 0.5)
+		}
+		return 0
+	},
+}
+
+// gaussian is a Gaussian blurring filter.
+var gaussian = resampleFilter{
+	Support: 2.0,
+	Kernel: func(x float64) float64 {
+		x = math.Abs(x)
+		if x < 2.0 {
+			return math.Exp(-2 * x * x)
+		}
+		return 0
+	},
+}
+
+func bcspline(x, b, c float64) float64 {
+	var y float64
+	x = math.Abs(x)
+	if x < 1.0 {
+		y = ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+	} else if x < 2.0 {
+		y = ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+	}
+	return y
+}
+
 //
 // scanner.go
 //
@@ -834,126 +982,27 @@ const (
 )
 
 // readOrientation tries to read the orientation EXIF flag from image data in r.
+// JPEG, PNG, TIFF and WebP containers are supported.
 // If the EXIF data block is not found or the orientation flag is not found
 // or any other error occures while reading the data, it returns the
 // orientationUnspecified (0) value.
-func readOrientation(r io.Reader) orientation {
-	const (
-		markerSOI      = 0xffd8
-		markerAPP1     = 0xffe1
-		exifHeader     = 0x45786966
-		byteOrderBE    = 0x4d4d
-		byteOrderLE    = 0x4949
-		orientationTag = 0x0112
-	)
-
-	// Check if JPEG SOI marker is present.
-	var soi uint16
-	if err := binary.Read(r, binary.BigEndian, &soi); err != nil {
-		return orientationUnspecified
-	}
-	if soi != markerSOI {
-		return orientationUnspecified // Missing JPEG SOI marker.
-	}
-
-	// Find JPEG APP1 marker.
-	for {
-		var marker, size uint16
-		if err := binary.Read(r, binary.BigEndian, &marker); err != nil {
-			return orientationUnspecified
-		}
-		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
-			return orientationUnspecified
-		}
-		if marker>>8 != 0xff {
-			return orientationUnspecified // Invalid JPEG marker.
+func readOrientation(r io.Reader) (o orientation) {
+	scanMetadata(r, func(kind MetadataKind, r io.Reader) bool {
+		if kind != EXIFMetadata {
+			return true
 		}
-		if marker == markerAPP1 {
-			break
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return false
 		}
-		if size < 2 {
-			return orientationUnspecified // Invalid block size.
-		}
-		if _, err := io.CopyN(io.Discard, r, int64(size-2)); err != nil {
-			return orientationUnspecified
-		}
-	}
-
-	// Check if EXIF header is present.
-	var header uint32
-	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
-		return orientationUnspecified
-	}
-	if header != exifHeader {
-		return orientationUnspecified
-	}
-	if _, err := io.CopyN(io.Discard, r, 2); err != nil {
-		return orientationUnspecified
-	}
-
-	// Read byte order information.
-	var (
-		byteOrderTag uint16
-		byteOrder    binary.ByteOrder
-	)
-	if err := binary.Read(r, binary.BigEndian, &byteOrderTag); err != nil {
-		return orientationUnspecified
-	}
-	switch byteOrderTag {
-	case byteOrderBE:
-		byteOrder = binary.BigEndian
-	case byteOrderLE:
-		byteOrder = binary.LittleEndian
-	default:
-		return orientationUnspecified // Invalid byte order flag.
-	}
-	if _, err := io.CopyN(io.Discard, r, 2); err != nil {
-		return orientationUnspecified
-	}
-
-	// Skip the EXIF offset.
-	var offset uint32
-	if err := binary.Read(r, byteOrder, &offset); err != nil {
-		return orientationUnspecified
-	}
-	if offset < 8 {
-		return orientationUnspecified // Invalid offset value.
-	}
-	if _, err := io.CopyN(io.Discard, r, int64(offset-8)); err != nil {
-		return orientationUnspecified
-	}
-
-	// Read the number of tags.
-	var numTags uint16
-	if err := binary.Read(r, byteOrder, &numTags); err != nil {
-		return orientationUnspecified
-	}
-
-	// Find the orientation tag.
-	for i := 0; i < int(numTags); i++ {
-		var tag uint16
-		if err := binary.Read(r, byteOrder, &tag); err != nil {
-			return orientationUnspecified
-		}
-		if tag != orientationTag {
-			if _, err := io.CopyN(io.Discard, r, 10); err != nil {
-				return orientationUnspecified
+		if d, err := parseExif(b); err == nil {
+			if v, ok := d.lookupUint(d.ifd0, tagOrientation); ok && v >= 1 && v <= 8 {
+				o = orientation(v)
 			}
-			continue
-		}
-		if _, err := io.CopyN(io.Discard, r, 6); err != nil {
-			return orientationUnspecified
-		}
-		var val uint16
-		if err := binary.Read(r, byteOrder, &val); err != nil {
-			return orientationUnspecified
 		}
-		if val < 1 || val > 8 {
-			return orientationUnspecified // Invalid tag value.
-		}
-		return orientation(val)
-	}
-	return orientationUnspecified // Missing orientation tag.
+		return false
+	})
+	return
 }
 
 // fixOrientation applies a transform to img corresponding to the given orientation flag.
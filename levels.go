@@ -0,0 +1,240 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// AutoLevelsOption is auto levels option
+type AutoLevelsOption struct {
+	// Clip is the percentage of the darkest and lightest pixels ignored when
+	// finding the black and white points, e.g. 0.5.
+	Clip float64
+	// PerChannel stretches each color channel separately, which also corrects
+	// color casts. Otherwise the levels are found on the luminance and the same
+	// stretch is applied to all channels, which keeps the hues.
+	PerChannel bool
+}
+
+func (l *AutoLevelsOption) do(base image.Image) image.Image {
+	return AutoLevels(base, l.Clip, l.PerChannel)
+}
+
+// CLAHEOption is contrast-limited adaptive histogram equalization option
+type CLAHEOption struct {
+	// Tiles is the number of tiles in each dimension, 8 if 0.
+	Tiles int
+	// ClipLimit limits the contrast enhancement as a multiple of the average
+	// histogram bin count, 2.0 if 0.
+	ClipLimit float64
+}
+
+func (c *CLAHEOption) do(base image.Image) image.Image {
+	return CLAHE(base, c.Tiles, c.ClipLimit)
+}
+
+// luma returns the luminance of the color as used by JPEG.
+func luma(r, g, b uint8) uint8 {
+	return uint8((19595*uint32(r) + 38470*uint32(g) + 7471*uint32(b) + 1<<15) >> 16)
+}
+
+// histograms returns the histograms of the red, green, blue and luminance values
+// of the pixels which are not fully transparent.
+func histograms(img *image.NRGBA) (hist [4][256]int) {
+	for y := range img.Rect.Dy() {
+		row := img.Pix[y*img.Stride : y*img.Stride+img.Rect.Dx()*4]
+		for i := 0; i < len(row); i += 4 {
+			if row[i+3] == 0 {
+				continue
+			}
+			hist[0][row[i]]++
+			hist[1][row[i+1]]++
+			hist[2][row[i+2]]++
+			hist[3][luma(row[i], row[i+1], row[i+2])]++
+		}
+	}
+	return
+}
+
+// percentiles returns the lowest and highest values of the histogram after
+// ignoring clip percent of the values at each end.
+func percentiles(hist *[256]int, clip float64) (low, high int) {
+	var total int
+	for _, n := range hist {
+		total += n
+	}
+	limit := int(float64(total) * min(max(clip, 0), 50) / 100)
+	low, high = 0, 255
+	for sum := 0; low < 255; low++ {
+		if sum += hist[low]; sum > limit {
+			break
+		}
+	}
+	for sum := 0; high > 0; high-- {
+		if sum += hist[high]; sum > limit {
+			break
+		}
+	}
+	return
+}
+
+// stretchLUT returns the table mapping low to 0 and high to 255.
+func stretchLUT(low, high int) []uint8 {
+	lut := make([]uint8, 256)
+	for i := range lut {
+		if high <= low {
+			lut[i] = uint8(i)
+		} else {
+			lut[i] = clamp(float64(i-low) * 255 / float64(high-low))
+		}
+	}
+	return lut
+}
+
+// AutoLevels stretches the levels of the image so that the darkest pixels become
+// black and the lightest become white, ignoring clip percent of the pixels at each
+// end. If perChannel is set, each color channel is stretched separately.
+func AutoLevels(img image.Image, clip float64, perChannel bool) image.Image {
+	dst := clone(img)
+	hist := histograms(dst)
+	var luts [3][]uint8
+	if perChannel {
+		for c := range luts {
+			luts[c] = stretchLUT(percentiles(&hist[c], clip))
+		}
+	} else {
+		lut := stretchLUT(percentiles(&hist[3], clip))
+		luts = [3][]uint8{lut, lut, lut}
+	}
+	parallel(0, dst.Rect.Dy(), func(ys <-chan int) {
+		for y := range ys {
+			row := dst.Pix[y*dst.Stride : y*dst.Stride+dst.Rect.Dx()*4]
+			for i := 0; i < len(row); i += 4 {
+				row[i] = luts[0][row[i]]
+				row[i+1] = luts[1][row[i+1]]
+				row[i+2] = luts[2][row[i+2]]
+			}
+		}
+	})
+	return dst
+}
+
+// equalizeLUT returns the table mapping values to their cumulative distribution.
+func equalizeLUT(hist *[256]int) []uint8 {
+	lut := make([]uint8, 256)
+	var total, first int
+	for _, n := range hist {
+		total += n
+	}
+	for _, n := range hist {
+		if n != 0 {
+			first = n
+			break
+		}
+	}
+	if total == first {
+		// A single value can not be spread.
+		for i := range lut {
+			lut[i] = uint8(i)
+		}
+		return lut
+	}
+	var sum int
+	for i, n := range hist {
+		sum += n
+		lut[i] = clamp(float64(sum-first) * 255 / float64(total-first))
+	}
+	return lut
+}
+
+// mapLuma replaces the luminance of each pixel of dst by fn in place, keeping the
+// chrominance and alpha.
+func mapLuma(dst *image.NRGBA, fn func(x, y int, luma uint8) uint8) *image.NRGBA {
+	parallel(0, dst.Rect.Dy(), func(ys <-chan int) {
+		for y := range ys {
+			row := dst.Pix[y*dst.Stride : y*dst.Stride+dst.Rect.Dx()*4]
+			for i := 0; i < len(row); i += 4 {
+				yy, cb, cr := color.RGBToYCbCr(row[i], row[i+1], row[i+2])
+				row[i], row[i+1], row[i+2] = color.YCbCrToRGB(fn(i/4, y, yy), cb, cr)
+			}
+		}
+	})
+	return dst
+}
+
+// Equalize applies global histogram equalization to the luminance of the image,
+// spreading the most frequent levels over the whole range.
+func Equalize(img image.Image) image.Image {
+	src := clone(img)
+	hist := histograms(src)
+	lut := equalizeLUT(&hist[3])
+	return mapLuma(src, func(_, _ int, v uint8) uint8 { return lut[v] })
+}
+
+// CLAHE applies contrast-limited adaptive histogram equalization to the luminance
+// of the image. The image is divided into tiles x tiles regions, each equalized
+// with its own histogram clipped at clipLimit times the average bin count, and the
+// results are interpolated between the centers of the regions.
+func CLAHE(img image.Image, tiles int, clipLimit float64) image.Image {
+	if tiles <= 0 {
+		tiles = 8
+	}
+	if clipLimit <= 0 {
+		clipLimit = 2
+	}
+	src := clone(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	tx, ty := max(min(tiles, w), 1), max(min(tiles, h), 1)
+
+	luts := make([][]uint8, tx*ty)
+	parallel(0, tx*ty, func(is <-chan int) {
+		for i := range is {
+			x0, x1 := i%tx*w/tx, (i%tx+1)*w/tx
+			y0, y1 := i/tx*h/ty, (i/tx+1)*h/ty
+			var hist [256]int
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride+x0*4 : y*src.Stride+x1*4]
+				for j := 0; j < len(row); j += 4 {
+					yy, _, _ := color.RGBToYCbCr(row[j], row[j+1], row[j+2])
+					hist[yy]++
+				}
+			}
+			area := (x1 - x0) * (y1 - y0)
+			// Clip the histogram and redistribute the excess evenly.
+			limit := max(1, int(clipLimit*float64(area)/256))
+			var excess int
+			for v, n := range hist {
+				if n > limit {
+					excess += n - limit
+					hist[v] = limit
+				}
+			}
+			for v := range hist {
+				hist[v] += excess / 256
+				if v < excess%256 {
+					hist[v]++
+				}
+			}
+			lut := make([]uint8, 256)
+			var sum int
+			for v, n := range hist {
+				sum += n
+				lut[v] = clamp(float64(sum) * 255 / float64(area))
+			}
+			luts[i] = lut
+		}
+	})
+
+	tileW, tileH := float64(w)/float64(tx), float64(h)/float64(ty)
+	return mapLuma(src, func(x, y int, v uint8) uint8 {
+		fx := min(max((float64(x)+0.5)/tileW-0.5, 0), float64(tx-1))
+		fy := min(max((float64(y)+0.5)/tileH-0.5, 0), float64(ty-1))
+		x0, y0 := int(fx), int(fy)
+		x1, y1 := min(x0+1, tx-1), min(y0+1, ty-1)
+		ax, ay := fx-float64(x0), fy-float64(y0)
+		top := float64(luts[y0*tx+x0][v])*(1-ax) + float64(luts[y0*tx+x1][v])*ax
+		bottom := float64(luts[y1*tx+x0][v])*(1-ax) + float64(luts[y1*tx+x1][v])*ax
+		return uint8(math.Round(top*(1-ay) + bottom*ay))
+	})
+}
@@ -0,0 +1,88 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// lowContrast returns a 16x16 image with gray levels from 100 to 131, with a
+// single dark and a single light pixel.
+func lowContrast() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := range 16 {
+		for x := range 16 {
+			v := uint8(100 + (y*16+x)/8)
+			img.SetNRGBA(x, y, color.NRGBA{v, v, v + 10, 255})
+		}
+	}
+	img.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 255})
+	img.SetNRGBA(15, 15, color.NRGBA{255, 255, 255, 255})
+	return img
+}
+
+func TestAutoLevels(t *testing.T) {
+	src := lowContrast()
+	if img := AutoLevels(src, 0, false); img.At(1, 0) != src.At(1, 0) {
+		t.Errorf("want levels unchanged without clipping, got %v", img.At(1, 0))
+	}
+
+	img := AutoLevels(src, 1, false).(*image.NRGBA)
+	dark, light := img.NRGBAAt(1, 0), img.NRGBAAt(14, 15)
+	if dark.R > 10 || light.R < 240 || dark.B <= dark.R {
+		t.Errorf("want stretched levels keeping the blue cast, got %v, %v", dark, light)
+	}
+
+	img = AutoLevels(src, 1, true).(*image.NRGBA)
+	if dark, light := img.NRGBAAt(1, 0), img.NRGBAAt(14, 15); dark.B > 10 || light.B < 240 || dark.R != dark.B {
+		t.Errorf("want stretched channels without the blue cast, got %v, %v", dark, light)
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, 2, 1))
+	gray.Pix = []uint8{50, 150}
+	img = AutoLevels(gray, 0, true).(*image.NRGBA)
+	if img.NRGBAAt(0, 0).R != 0 || img.NRGBAAt(1, 0).R != 255 {
+		t.Errorf("want full range, got %v, %v", img.NRGBAAt(0, 0), img.NRGBAAt(1, 0))
+	}
+}
+
+func TestEqualize(t *testing.T) {
+	img := Equalize(lowContrast()).(*image.NRGBA)
+	if dark, mid, light := img.NRGBAAt(1, 0), img.NRGBAAt(0, 8), img.NRGBAAt(14, 15); dark.G > 10 || light.G < 245 || mid.G < 110 || mid.G > 145 {
+		t.Errorf("want spread levels, got %v, %v, %v", dark, mid, light)
+	}
+
+	flat := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0x80
+	}
+	compare(t, flat, Equalize(flat))
+}
+
+func TestCLAHE(t *testing.T) {
+	// Left half dark and right half light, each with low contrast detail.
+	src := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for y := range 32 {
+		for x := range 64 {
+			v := uint8(40 + (x+y)%2*10)
+			if x >= 32 {
+				v += 160
+			}
+			src.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	img := CLAHE(src, 2, 4).(*image.NRGBA)
+	d0, d1 := img.NRGBAAt(4, 4), img.NRGBAAt(5, 4)
+	if int(d1.G)-int(d0.G) <= 10 {
+		t.Errorf("want enhanced local contrast, got %v, %v", d0, d1)
+	}
+	if img.NRGBAAt(60, 4).G <= img.NRGBAAt(4, 4).G {
+		t.Error("want light half kept lighter")
+	}
+	if c := img.NRGBAAt(0, 0); c.A != 255 || c.R != c.B {
+		t.Errorf("want gray opaque pixel, got %v", c)
+	}
+	if img := CLAHE(image.NewNRGBA(image.Rect(0, 0, 3, 1)), 0, 0); img.Bounds().Dx() != 3 {
+		t.Errorf("want 3x1 image, got %v", img.Bounds())
+	}
+}
@@ -0,0 +1,131 @@
+package imgconv
+
+import (
+	"image"
+	"math"
+	"slices"
+	"sync"
+)
+
+// linearLUT maps 8-bit sRGB values to linear light.
+var linearLUT = sync.OnceValue(func() (lut [256]float32) {
+	for i := range lut {
+		lut[i] = float32(srgbDecode(float64(i) / 255))
+	}
+	return
+})
+
+// srgbLUT maps linear light in [0, 1] with 1<<14 steps to 8-bit sRGB values.
+var srgbLUT = sync.OnceValue(func() []uint8 {
+	lut := make([]uint8, 1<<14+1)
+	for i := range lut {
+		lut[i] = uint8(math.Round(srgbEncode(float64(i)/(1<<14)) * 255))
+	}
+	return lut
+})
+
+// resizeLinear works like resize, but resamples in linear light with premultiplied
+// alpha and float32 intermediate buffers, so that bright details and edges between
+// saturated colors keep their brightness.
+func resizeLinear(img image.Image, width, height int, filter resampleFilter) *image.NRGBA {
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	if filter.Support <= 0 || width < 0 || height < 0 || width == 0 && height == 0 || srcW <= 0 || srcH <= 0 {
+		// Nearest neighbor does not blend pixels.
+		return resize(img, width, height, filter)
+	}
+	if width == 0 {
+		width = max(1, int(math.Floor(float64(height)*float64(srcW)/float64(srcH)+0.5)))
+	}
+	if height == 0 {
+		height = max(1, int(math.Floor(float64(width)*float64(srcH)/float64(srcW)+0.5)))
+	}
+	if width == srcW && height == srcH {
+		return clone(img)
+	}
+
+	// The result is computed in bands of rows, each from the source rows it needs
+	// converted to linear light and resampled horizontally, which keeps the float32
+	// buffers small.
+	const bandSize = 64
+	hWeights := linearWeights(width, srcW, filter)
+	vWeights := linearWeights(height, srcH, filter)
+	lut, enc := linearLUT(), srgbLUT()
+	src := newScanner(img)
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	parallel(0, (height+bandSize-1)/bandSize, func(bands <-chan int) {
+		scanLine := make([]uint8, srcW*4)
+		line := make([]float32, srcW*4)
+		acc := make([]float32, width*4)
+		var rows []float32
+		for band := range bands {
+			y0, y1 := band*bandSize, min((band+1)*bandSize, height)
+			first, last := srcH, -1
+			for _, weights := range vWeights[y0:y1] {
+				for _, iw := range weights {
+					first, last = min(first, iw.index), max(last, iw.index)
+				}
+			}
+			if last < first {
+				continue
+			}
+			rows = slices.Grow(rows[:0], (last-first+1)*width*4)[:(last-first+1)*width*4]
+			for y := first; y <= last; y++ {
+				src.scan(0, y, srcW, y+1, scanLine)
+				for i := 0; i < len(scanLine); i += 4 {
+					a := float32(scanLine[i+3]) / 255
+					line[i] = lut[scanLine[i]] * a
+					line[i+1] = lut[scanLine[i+1]] * a
+					line[i+2] = lut[scanLine[i+2]] * a
+					line[i+3] = a
+				}
+				d := rows[(y-first)*width*4 : (y-first+1)*width*4]
+				for x := range hWeights {
+					var r, g, b, a float32
+					for _, iw := range hWeights[x] {
+						i, wt := iw.index*4, float32(iw.weight)
+						r += line[i] * wt
+						g += line[i+1] * wt
+						b += line[i+2] * wt
+						a += line[i+3] * wt
+					}
+					d[x*4], d[x*4+1], d[x*4+2], d[x*4+3] = r, g, b, a
+				}
+			}
+
+			for y := y0; y < y1; y++ {
+				clear(acc)
+				for _, iw := range vWeights[y] {
+					s, wt := rows[(iw.index-first)*width*4:(iw.index-first+1)*width*4], float32(iw.weight)
+					for i := range acc {
+						acc[i] += s[i] * wt
+					}
+				}
+				d := dst.Pix[y*dst.Stride : y*dst.Stride+width*4]
+				for i := 0; i < len(acc); i += 4 {
+					a := min(acc[i+3], 1)
+					if a <= 0 {
+						continue
+					}
+					for c := range 3 {
+						d[i+c] = enc[int(min(max(acc[i+c]/a, 0), 1)*(1<<14)+0.5)]
+					}
+					d[i+3] = uint8(a*255 + 0.5)
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// linearWeights returns the weights resampling srcSize pixels to dstSize pixels,
+// which keep the pixels unchanged if the sizes are equal.
+func linearWeights(dstSize, srcSize int, filter resampleFilter) [][]indexWeight {
+	if dstSize != srcSize {
+		return precomputeWeights(dstSize, srcSize, filter)
+	}
+	weights := make([][]indexWeight, dstSize)
+	for i := range weights {
+		weights[i] = []indexWeight{{index: i, weight: 1}}
+	}
+	return weights
+}
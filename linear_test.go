@@ -0,0 +1,55 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResizeLinear(t *testing.T) {
+	// Alternating black and white columns average to 50% linear light, which is
+	// encoded as 188 in sRGB, not 128.
+	src := image.NewNRGBA(image.Rect(0, 0, 8, 2))
+	for y := range 2 {
+		for x := range 8 {
+			if x%2 == 0 {
+				src.SetNRGBA(x, y, color.NRGBA{255, 255, 255, 255})
+			} else {
+				src.SetNRGBA(x, y, color.NRGBA{0, 0, 0, 255})
+			}
+		}
+	}
+	option := &ResizeOption{Width: 1, Height: 1, Filter: Box}
+	if c := Resize(src, option).(*image.NRGBA).NRGBAAt(0, 0); c.R < 127 || c.R > 129 {
+		t.Errorf("want sRGB average, got %v", c)
+	}
+	if c := Resize(src, option.SetLinearLight(true)).(*image.NRGBA).NRGBAAt(0, 0); c != (color.NRGBA{188, 188, 188, 255}) {
+		t.Errorf("want linear light average, got %v", c)
+	}
+
+	// Fully transparent pixels do not bleed their color.
+	src = image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	src.SetNRGBA(1, 0, color.NRGBA{0, 255, 0, 0})
+	if c := resizeLinear(src, 1, 1, box).NRGBAAt(0, 0); c != (color.NRGBA{255, 0, 0, 128}) {
+		t.Errorf("want red with half alpha, got %v", c)
+	}
+
+	sample, err := Open("testdata/video-001.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		width, height int
+		want          image.Point
+	}{
+		{300, 0, image.Pt(300, 206)},
+		{0, 52, image.Pt(76, 52)},
+		{150, 103, image.Pt(150, 103)},
+	} {
+		if got := resizeLinear(sample, tc.width, tc.height, lanczos).Bounds().Size(); got != tc.want {
+			t.Errorf("want %v, got %v", tc.want, got)
+		}
+	}
+	compare(t, sample, resizeLinear(sample, 150, 103, lanczos))
+}
@@ -0,0 +1,124 @@
+package imgconv
+
+import (
+	"image"
+	"math"
+)
+
+// MaskOption is mask option
+type MaskOption struct {
+	// CornerRadius is the radius of the rounded corners in pixels.
+	CornerRadius float64
+	// Circle masks the image with the largest circle centered in it, instead of
+	// rounding its corners.
+	Circle bool
+}
+
+func (m *MaskOption) do(base image.Image) image.Image {
+	if m.Circle {
+		return CircleMask(base)
+	}
+	return RoundCorners(base, m.CornerRadius)
+}
+
+// VignetteOption is vignette option
+type VignetteOption struct {
+	// Strength is the percentage by which the corners are darkened (0-100).
+	Strength float64
+	// Radius is the distance from the center where the darkening starts, relative
+	// to half the diagonal (0-1), 0.5 if 0.
+	Radius float64
+}
+
+func (v *VignetteOption) do(base image.Image) image.Image {
+	return Vignette(base, v.Strength, v.Radius)
+}
+
+// coverage returns the part of a pixel covered by a shape, given the distance
+// from the pixel center to the edge of the shape, positive inside.
+func coverage(distance float64) float64 {
+	return min(max(distance+0.5, 0), 1)
+}
+
+// mask multiplies the alpha of each pixel of the image by fn applied to its center.
+func mask(img image.Image, fn func(x, y float64) float64) *image.NRGBA {
+	dst := clone(img)
+	parallel(0, dst.Rect.Dy(), func(ys <-chan int) {
+		for y := range ys {
+			row := dst.Pix[y*dst.Stride : y*dst.Stride+dst.Rect.Dx()*4]
+			for x := range dst.Rect.Dx() {
+				if c := fn(float64(x)+0.5, float64(y)+0.5); c < 1 {
+					row[x*4+3] = clamp(float64(row[x*4+3]) * c)
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// RoundCorners rounds the corners of the image with the radius in pixels, making
+// them transparent with anti-aliased edges. The radius is limited to half the
+// shorter side of the image.
+func RoundCorners(img image.Image, radius float64) image.Image {
+	w, h := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	radius = min(radius, w/2, h/2)
+	if radius <= 0 {
+		return clone(img)
+	}
+	return mask(img, func(x, y float64) float64 {
+		// The distance to the rectangle inset by the radius.
+		cx, cy := min(max(x, radius), w-radius), min(max(y, radius), h-radius)
+		if x == cx || y == cy {
+			return 1
+		}
+		return coverage(radius - math.Hypot(x-cx, y-cy))
+	})
+}
+
+// CircleMask makes the pixels of the image outside the largest circle centered in
+// it transparent, with an anti-aliased edge. Crop the image to a square first for
+// round avatars.
+func CircleMask(img image.Image) image.Image {
+	w, h := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	radius := min(w, h) / 2
+	return mask(img, func(x, y float64) float64 {
+		return coverage(radius - math.Hypot(x-w/2, y-h/2))
+	})
+}
+
+// Vignette darkens the image progressively from radius, relative to half the
+// diagonal (0-1), to the corners, which are darkened by strength percent (0-100).
+// A radius of 0 uses 0.5.
+func Vignette(img image.Image, strength, radius float64) image.Image {
+	strength = min(max(strength, 0), 100) / 100
+	if radius <= 0 {
+		radius = 0.5
+	}
+	radius = min(radius, 1)
+	dst := clone(img)
+	w, h := float64(dst.Rect.Dx()), float64(dst.Rect.Dy())
+	diag := math.Hypot(w, h) / 2
+	if strength == 0 || diag == 0 {
+		return dst
+	}
+	parallel(0, dst.Rect.Dy(), func(ys <-chan int) {
+		for y := range ys {
+			row := dst.Pix[y*dst.Stride : y*dst.Stride+dst.Rect.Dx()*4]
+			for x := range dst.Rect.Dx() {
+				d := math.Hypot(float64(x)+0.5-w/2, float64(y)+0.5-h/2) / diag
+				if d <= radius {
+					continue
+				}
+				t := 1.0
+				if radius < 1 {
+					t = min((d-radius)/(1-radius), 1)
+				}
+				// Smooth step from no darkening to strength.
+				f := 1 - strength*t*t*(3-2*t)
+				p := row[x*4 : x*4+3 : x*4+3]
+				p[0], p[1], p[2] = clamp(float64(p[0])*f), clamp(float64(p[1])*f), clamp(float64(p[2])*f)
+			}
+		}
+	})
+	return dst
+}
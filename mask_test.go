@@ -0,0 +1,80 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func whiteImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	return img
+}
+
+func TestRoundCorners(t *testing.T) {
+	src := whiteImage(20, 10)
+	img := RoundCorners(src, 4).(*image.NRGBA)
+	for _, p := range []image.Point{{0, 0}, {19, 0}, {0, 9}, {19, 9}} {
+		if a := img.NRGBAAt(p.X, p.Y).A; a != 0 {
+			t.Errorf("want transparent corner at %v, got alpha %d", p, a)
+		}
+	}
+	for _, p := range []image.Point{{4, 0}, {15, 9}, {0, 4}, {10, 5}, {2, 2}} {
+		if a := img.NRGBAAt(p.X, p.Y).A; a != 255 {
+			t.Errorf("want opaque pixel at %v, got alpha %d", p, a)
+		}
+	}
+	// The edge is anti-aliased.
+	if a := img.NRGBAAt(1, 1).A; a == 0 || a == 255 {
+		t.Errorf("want partial alpha, got %d", a)
+	}
+	if c := img.NRGBAAt(1, 1); c.R != 255 {
+		t.Errorf("want color kept, got %v", c)
+	}
+
+	// The radius is limited to half the shorter side.
+	if a := RoundCorners(src, 100).(*image.NRGBA).NRGBAAt(1, 0).A; a != 0 {
+		t.Errorf("want transparent pixel, got alpha %d", a)
+	}
+	compare(t, src, RoundCorners(src, 0))
+}
+
+func TestCircleMask(t *testing.T) {
+	img := CircleMask(whiteImage(30, 20)).(*image.NRGBA)
+	for _, tc := range []struct {
+		x, y  int
+		alpha uint8
+	}{
+		{15, 10, 255},
+		{4, 10, 0},
+		{6, 10, 255},
+		{15, 1, 255},
+		{7, 2, 0},
+		{29, 19, 0},
+	} {
+		if a := img.NRGBAAt(tc.x, tc.y).A; a != tc.alpha {
+			t.Errorf("want alpha %d at (%d, %d), got %d", tc.alpha, tc.x, tc.y, a)
+		}
+	}
+}
+
+func TestVignette(t *testing.T) {
+	src := whiteImage(21, 21)
+	img := Vignette(src, 50, 0).(*image.NRGBA)
+	if c := img.NRGBAAt(10, 10); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("want center unchanged, got %v", c)
+	}
+	corner, edge := img.NRGBAAt(0, 0), img.NRGBAAt(0, 10)
+	if corner.R < 120 || corner.R > 140 || corner.A != 255 {
+		t.Errorf("want corner darkened by about 50%%, got %v", corner)
+	}
+	if edge.R <= corner.R || edge.R == 255 {
+		t.Errorf("want edge darkened less than corner, got %v", edge)
+	}
+	compare(t, src, Vignette(src, 0, 0.5))
+
+	img = (&VignetteOption{Strength: 100, Radius: 1}).do(src).(*image.NRGBA)
+	compare(t, src, img)
+}
@@ -0,0 +1,287 @@
+package imgconv
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	_ encoding.TextUnmarshaler = new(MetadataKind)
+	_ encoding.TextMarshaler   = MetadataKind(0)
+)
+
+// MetadataKind is a set of metadata kinds.
+type MetadataKind int
+
+// Metadata kinds.
+const (
+	EXIFMetadata MetadataKind = 1 << iota
+	XMPMetadata
+	ICCMetadata
+	DPIMetadata
+
+	NoMetadata  MetadataKind = 0
+	AllMetadata              = EXIFMetadata | XMPMetadata | ICCMetadata | DPIMetadata
+)
+
+var metadataKinds = []string{
+	"exif",
+	"xmp",
+	"icc",
+	"dpi",
+}
+
+// UnmarshalText parses a comma-separated list of metadata kinds:
+// "exif", "xmp", "icc", "dpi", "all" and "none" are supported.
+func (k *MetadataKind) UnmarshalText(text []byte) error {
+	var kind MetadataKind
+	for s := range strings.SplitSeq(strings.ToLower(string(text)), ",") {
+		switch s = strings.TrimSpace(s); s {
+		case "", "none":
+		case "all":
+			kind |= AllMetadata
+		default:
+			i := slices.Index(metadataKinds, s)
+			if i < 0 {
+				return fmt.Errorf("unsupported metadata kind: %s", s)
+			}
+			kind |= 1 << i
+		}
+	}
+	*k = kind
+	return nil
+}
+
+func (k MetadataKind) MarshalText() ([]byte, error) {
+	if k&^AllMetadata != 0 {
+		return []byte("unknown"), nil
+	}
+	var kinds []string
+	for i, s := range metadataKinds {
+		if k&(1<<i) != 0 {
+			kinds = append(kinds, s)
+		}
+	}
+	if len(kinds) == 0 {
+		return []byte("none"), nil
+	}
+	return []byte(strings.Join(kinds, ",")), nil
+}
+
+// Metadata represents the metadata embedded in an image file.
+type Metadata struct {
+	EXIF *EXIF
+	XMP  []byte
+	ICC  []byte
+	// DPI is the horizontal resolution in dots per inch, or 0 if unknown.
+	DPI float64
+}
+
+// EXIF represents the commonly used fields of the EXIF metadata.
+// Fields not present in the image are left zero.
+type EXIF struct {
+	Make     string
+	Model    string
+	Software string
+
+	LensMake  string
+	LensModel string
+
+	ExposureTime      float64 // in seconds
+	FNumber           float64
+	ISO               int
+	FocalLength       float64 // in millimeters
+	FocalLengthIn35mm int     // in millimeters
+
+	// DateTimeOriginal is the time the photo was taken. If no time offset is
+	// recorded, the time is in the local time zone.
+	DateTimeOriginal time.Time
+
+	Width       int
+	Height      int
+	Orientation int
+
+	GPS *GPS
+
+	data *exifData
+}
+
+// GPS represents the location recorded in the EXIF metadata.
+type GPS struct {
+	Latitude  float64 // in degrees, negative for south
+	Longitude float64 // in degrees, negative for west
+	Altitude  float64 // in meters, negative for below sea level
+}
+
+// ReadMetadata reads the EXIF, XMP and ICC metadata and the resolution from the image
+// data in r. JPEG, PNG, TIFF, WebP and BMP containers are supported.
+// Metadata that is not present in the image or malformed EXIF metadata is left nil.
+func ReadMetadata(r io.Reader) (*Metadata, error) {
+	md := new(Metadata)
+	var err error
+	if !scanMetadata(r, func(kind MetadataKind, r io.Reader) bool {
+		var b []byte
+		if b, err = io.ReadAll(r); err != nil {
+			return false
+		}
+		switch kind {
+		case EXIFMetadata:
+			// Malformed EXIF blocks are skipped to keep the other metadata.
+			if x, err := newEXIF(b); err == nil && md.EXIF == nil {
+				md.EXIF = x
+			}
+		case XMPMetadata:
+			if md.XMP == nil {
+				md.XMP = b
+			}
+		case ICCMetadata:
+			if md.ICC == nil {
+				md.ICC = b
+			}
+		case DPIMetadata:
+			if md.DPI == 0 && len(b) == 8 {
+				md.DPI = math.Float64frombits(binary.BigEndian.Uint64(b))
+			}
+		}
+		return err == nil
+	}) {
+		return nil, image.ErrFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// TIFF containers store XMP and ICC metadata as IFD0 fields.
+	if x := md.EXIF; x != nil {
+		if e, ok := x.data.ifd0.find(tagXMP); ok && md.XMP == nil {
+			md.XMP = e.value
+		}
+		if e, ok := x.data.ifd0.find(tagICCProfile); ok && md.ICC == nil {
+			md.ICC = e.value
+		}
+		if md.DPI == 0 {
+			md.DPI = x.data.dpi()
+		}
+	}
+	return md, nil
+}
+
+// Select returns a copy of md holding only the metadata of the given kinds.
+func (md *Metadata) Select(kind MetadataKind) *Metadata {
+	if md == nil {
+		return nil
+	}
+	selected := new(Metadata)
+	if kind&EXIFMetadata != 0 {
+		selected.EXIF = md.EXIF
+	}
+	if kind&XMPMetadata != 0 {
+		selected.XMP = md.XMP
+	}
+	if kind&ICCMetadata != 0 {
+		selected.ICC = md.ICC
+	}
+	if kind&DPIMetadata != 0 {
+		selected.DPI = md.DPI
+	}
+	return selected
+}
+
+// OpenMetadata reads the metadata from file.
+func OpenMetadata(file string) (*Metadata, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadMetadata(f)
+}
+
+func newEXIF(b []byte) (*EXIF, error) {
+	d, err := parseExif(b)
+	if err != nil {
+		return nil, err
+	}
+	return exifFromData(d), nil
+}
+
+func exifFromData(d *exifData) *EXIF {
+	x := &EXIF{
+		Make:             d.lookupString(d.ifd0, tagMake),
+		Model:            d.lookupString(d.ifd0, tagModel),
+		Software:         d.lookupString(d.ifd0, tagSoftware),
+		LensMake:         d.lookupString(d.exif, tagLensMake),
+		LensModel:        d.lookupString(d.exif, tagLensModel),
+		DateTimeOriginal: d.lookupTime(d.exif, tagDateTimeOriginal, tagOffsetTimeOriginal),
+		data:             d,
+	}
+	x.ExposureTime, _ = d.lookupFloat(d.exif, tagExposureTime)
+	x.FNumber, _ = d.lookupFloat(d.exif, tagFNumber)
+	x.FocalLength, _ = d.lookupFloat(d.exif, tagFocalLength)
+	if v, ok := d.lookupUint(d.exif, tagISOSpeed); ok {
+		x.ISO = int(v)
+	}
+	if v, ok := d.lookupUint(d.exif, tagFocalLengthIn35mmFilm); ok {
+		x.FocalLengthIn35mm = int(v)
+	}
+	if v, ok := d.lookupUint(d.ifd0, tagOrientation); ok && v >= 1 && v <= 8 {
+		x.Orientation = int(v)
+	}
+
+	if w, ok := d.lookupUint(d.exif, tagPixelXDimension); ok {
+		x.Width = int(w)
+	} else if w, ok := d.lookupUint(d.ifd0, tagImageWidth); ok {
+		x.Width = int(w)
+	}
+	if h, ok := d.lookupUint(d.exif, tagPixelYDimension); ok {
+		x.Height = int(h)
+	} else if h, ok := d.lookupUint(d.ifd0, tagImageLength); ok {
+		x.Height = int(h)
+	}
+
+	lat, latOK := d.lookupCoordinate(tagGPSLatitude, tagGPSLatitudeRef, "S")
+	lon, lonOK := d.lookupCoordinate(tagGPSLongitude, tagGPSLongitudeRef, "W")
+	if latOK && lonOK {
+		x.GPS = &GPS{Latitude: lat, Longitude: lon}
+		if alt, ok := d.lookupFloat(d.gps, tagGPSAltitude); ok {
+			if ref, _ := d.lookupUint(d.gps, tagGPSAltitudeRef); ref == 1 {
+				alt = -alt
+			}
+			x.GPS.Altitude = alt
+		}
+	}
+
+	return x
+}
+
+// exifData returns the EXIF fields to be written to an image of the given size.
+// The orientation tag is taken from the Orientation field and the pixel dimensions
+// are updated to the new size.
+func (x *EXIF) exifData(width, height int) *exifData {
+	if x == nil || x.data == nil {
+		return nil
+	}
+	d := *x.data
+	d.ifd0 = d.ifd0.delete(tiffStructureTags...)
+	if x.Orientation > 0 {
+		d.ifd0 = d.ifd0.set(exifEntry{tagOrientation, exifShort, 1, d.order.AppendUint16(nil, uint16(x.Orientation))})
+	} else {
+		d.ifd0 = d.ifd0.delete(tagOrientation)
+	}
+	if _, ok := d.exif.find(tagPixelXDimension); ok {
+		d.exif = d.exif.set(exifEntry{tagPixelXDimension, exifLong, 1, d.order.AppendUint32(nil, uint32(width))})
+	}
+	if _, ok := d.exif.find(tagPixelYDimension); ok {
+		d.exif = d.exif.set(exifEntry{tagPixelYDimension, exifLong, 1, d.order.AppendUint32(nil, uint32(height))})
+	}
+	return &d
+}
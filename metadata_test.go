@@ -0,0 +1,111 @@
+package imgconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"image"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestReadMetadata(t *testing.T) {
+	exif := sampleExif(binary.LittleEndian)
+	for name, data := range map[string][]byte{
+		"jpeg": jpegWithExif(exif),
+		"png":  pngWithExif(t, image.NewGray(image.Rect(0, 0, 2, 1)), exif),
+		"tiff": exif,
+		"webp": webpWithExif(exif),
+	} {
+		md, err := ReadMetadata(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if md.EXIF == nil || md.EXIF.Model != "Canon EOS R5" || md.EXIF.GPS == nil {
+			t.Errorf("%s: wrong EXIF: %+v", name, md.EXIF)
+		}
+	}
+
+	md, err := ReadMetadata(bytes.NewReader(jpegWithExif(exif)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(md.XMP) != "<x:xmpmeta/>" {
+		t.Errorf("wrong XMP: %q", md.XMP)
+	}
+
+	md, err = OpenMetadata("testdata/video-001.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.EXIF != nil || md.XMP != nil || md.ICC != nil {
+		t.Errorf("want no metadata, got %+v", md)
+	}
+
+	// Only the beginning of large TIFF files is read.
+	pixels := make([]byte, maxMetadataSize)
+	md, err = ReadMetadata(io.MultiReader(bytes.NewReader(exif), bytes.NewReader(pixels)))
+	if err != nil || md.EXIF == nil || md.EXIF.Model != "Canon EOS R5" {
+		t.Errorf("want EXIF of large TIFF, got %+v, %v", md, err)
+	}
+	tiff := binary.LittleEndian.AppendUint32(slices.Clone(tiffMagicLE), maxMetadataSize+8)
+	md, err = ReadMetadata(io.MultiReader(bytes.NewReader(tiff), bytes.NewReader(pixels), bytes.NewReader(exif[8:])))
+	if err != nil || md.EXIF != nil {
+		t.Errorf("want no EXIF beyond the limit, got %+v, %v", md, err)
+	}
+
+	// Malformed EXIF metadata is skipped.
+	var buf bytes.Buffer
+	if err := (&FormatOption{PNG, []EncodeOption{EmbedMetadata(&Metadata{ICC: srgbProfile()})}}).Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 2, 1))); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	ihdrEnd := len(pngMagic) + 8 + 13 + 4
+	b = slices.Concat(b[:ihdrEnd], appendPNGChunk(nil, "eXIf", exif[:20]), b[ihdrEnd:])
+	md, err = ReadMetadata(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.EXIF != nil || !bytes.Equal(md.ICC, srgbProfile()) {
+		t.Errorf("want ICC profile without EXIF, got %+v", md)
+	}
+
+	if _, err := ReadMetadata(bytes.NewBufferString("Hello")); err != image.ErrFormat {
+		t.Errorf("want %v, got %v", image.ErrFormat, err)
+	}
+	if _, err := OpenMetadata("/invalid/path"); err == nil {
+		t.Error("OpenMetadata invalid path want error")
+	}
+}
+
+func TestMetadataKind(t *testing.T) {
+	for _, tc := range []struct {
+		argument string
+		kind     MetadataKind
+		text     string
+	}{
+		{"none", NoMetadata, "none"},
+		{"EXIF", EXIFMetadata, "exif"},
+		{"icc, xmp", XMPMetadata | ICCMetadata, "xmp,icc"},
+		{"all", AllMetadata, "exif,xmp,icc,dpi"},
+		{"gps", MetadataKind(-1), "unknown"},
+	} {
+		f := flag.NewFlagSet("test", flag.ContinueOnError)
+		f.SetOutput(io.Discard)
+		var kind MetadataKind
+		f.TextVar(&kind, "m", MetadataKind(-1), "")
+		f.Parse(append([]string{"-m"}, tc.argument))
+		if kind != tc.kind {
+			t.Errorf("expected %d metadata kind; got %d", tc.kind, kind)
+		}
+		if text, _ := kind.MarshalText(); string(text) != tc.text {
+			t.Errorf("expected %q; got %q", tc.text, text)
+		}
+	}
+
+	md := &Metadata{EXIF: new(EXIF), XMP: []byte("xmp"), ICC: []byte("icc")}
+	if s := md.Select(XMPMetadata); s.EXIF != nil || s.XMP == nil || s.ICC != nil {
+		t.Errorf("wrong selected metadata: %+v", s)
+	}
+}
@@ -2,6 +2,7 @@ package imgconv
 
 import (
 	"image"
+	"image/color"
 	"io"
 	"path/filepath"
 )
@@ -12,10 +13,31 @@ var defaultFormat = &FormatOption{Format: JPEG}
 
 // Options represents options that can be used to configure a image operation.
 type Options struct {
+	Deskew    *DeskewOption
+	Trim      *TrimOption
+	Crop      *CropOption
+	Flip      FlipMode
+	Rotate    *RotateOption
 	Watermark *WatermarkOption
+	Mask      *MaskOption
 	Resize    *ResizeOption
+	Levels    *AutoLevelsOption
+	Equalize  bool
+	CLAHE     *CLAHEOption
+	Adjust    *AdjustOption
+	Effect    []EffectOption
+	Blur      float64
+	Sharpen   *SharpenOption
+	Vignette  *VignetteOption
+	Pad       *PadOption
+	Threshold *ThresholdOption
 	Format    *FormatOption
 	Gray      bool
+	// GrayOption is the grayscale conversion option used if Gray is set,
+	// default if nil.
+	GrayOption *GrayOption
+	Metadata   MetadataKind
+	Scrub      ScrubPolicy
 }
 
 // NewOptions creates a new option with default setting.
@@ -23,6 +45,36 @@ func NewOptions() *Options {
 	return &Options{Format: defaultFormat}
 }
 
+// SetDeskew sets the value for the Deskew field.
+func (opts *Options) SetDeskew(maxAngle float64, bgColor color.Color) *Options {
+	opts.Deskew = &DeskewOption{MaxAngle: maxAngle, Background: bgColor}
+	return opts
+}
+
+// SetTrim sets the value for the Trim field.
+func (opts *Options) SetTrim(tolerance uint8, padding int) *Options {
+	opts.Trim = &TrimOption{Tolerance: tolerance, Padding: padding}
+	return opts
+}
+
+// SetCrop sets the value for the Crop field.
+func (opts *Options) SetCrop(width, height int, anchor Anchor) *Options {
+	opts.Crop = &CropOption{Width: width, Height: height, Anchor: anchor}
+	return opts
+}
+
+// SetFlip sets the value for the Flip field.
+func (opts *Options) SetFlip(mode FlipMode) *Options {
+	opts.Flip = mode
+	return opts
+}
+
+// SetRotate sets the value for the Rotate field.
+func (opts *Options) SetRotate(angle float64, bgColor color.Color) *Options {
+	opts.Rotate = &RotateOption{Angle: angle, Background: bgColor}
+	return opts
+}
+
 // SetWatermark sets the value for the Watermark field.
 func (opts *Options) SetWatermark(mark image.Image, opacity uint) *Options {
 	opts.Watermark = &WatermarkOption{Mark: mark}
@@ -41,6 +93,79 @@ func (opts *Options) SetResize(width, height int, percent float64) *Options {
 	return opts
 }
 
+// SetAutoLevels sets the value for the Levels field.
+func (opts *Options) SetAutoLevels(clip float64, perChannel bool) *Options {
+	opts.Levels = &AutoLevelsOption{Clip: clip, PerChannel: perChannel}
+	return opts
+}
+
+// SetEqualize sets the value for the Equalize field.
+func (opts *Options) SetEqualize(equalize bool) *Options {
+	opts.Equalize = equalize
+	return opts
+}
+
+// SetCLAHE sets the value for the CLAHE field.
+func (opts *Options) SetCLAHE(tiles int, clipLimit float64) *Options {
+	opts.CLAHE = &CLAHEOption{Tiles: tiles, ClipLimit: clipLimit}
+	return opts
+}
+
+// SetAdjust sets the value for the Adjust field.
+func (opts *Options) SetAdjust(option AdjustOption) *Options {
+	opts.Adjust = &option
+	return opts
+}
+
+// SetEffect sets the value for the Effect field. The options are applied in
+// the given order.
+func (opts *Options) SetEffect(options ...EffectOption) *Options {
+	opts.Effect = options
+	return opts
+}
+
+// SetBlur sets the value for the Blur field.
+func (opts *Options) SetBlur(sigma float64) *Options {
+	opts.Blur = sigma
+	return opts
+}
+
+// SetSharpen sets the value for the Sharpen field.
+func (opts *Options) SetSharpen(radius, amount float64, threshold uint8) *Options {
+	opts.Sharpen = &SharpenOption{Radius: radius, Amount: amount, Threshold: threshold}
+	return opts
+}
+
+// SetVignette sets the value for the Vignette field.
+func (opts *Options) SetVignette(strength, radius float64) *Options {
+	opts.Vignette = &VignetteOption{Strength: strength, Radius: radius}
+	return opts
+}
+
+// SetPad sets the value for the Pad field.
+func (opts *Options) SetPad(top, right, bottom, left int, bgColor color.Color) *Options {
+	opts.Pad = &PadOption{Top: top, Right: right, Bottom: bottom, Left: left, Background: bgColor}
+	return opts
+}
+
+// SetRoundCorners sets the value for the Mask field to round the corners.
+func (opts *Options) SetRoundCorners(radius float64) *Options {
+	opts.Mask = &MaskOption{CornerRadius: radius}
+	return opts
+}
+
+// SetCircleMask sets the value for the Mask field to mask with a circle.
+func (opts *Options) SetCircleMask() *Options {
+	opts.Mask = &MaskOption{Circle: true}
+	return opts
+}
+
+// SetThreshold sets the value for the Threshold field.
+func (opts *Options) SetThreshold(method ThresholdMethod, level uint8) *Options {
+	opts.Threshold = &ThresholdOption{Method: method, Level: level}
+	return opts
+}
+
 // SetFormat sets the value for the Format field.
 func (opts *Options) SetFormat(f Format, options ...EncodeOption) *Options {
 	opts.Format = &FormatOption{f, options}
@@ -53,23 +178,111 @@ func (opts *Options) SetGray(gray bool) *Options {
 	return opts
 }
 
+// SetGrayMode sets the Gray field and the value for the GrayOption field.
+func (opts *Options) SetGrayMode(mode GrayMode, alpha bool) *Options {
+	opts.Gray = true
+	opts.GrayOption = &GrayOption{Mode: mode, Alpha: alpha}
+	return opts
+}
+
+// SetMetadata sets the value for the Metadata field.
+func (opts *Options) SetMetadata(kind MetadataKind) *Options {
+	opts.Metadata = kind
+	return opts
+}
+
+// SetScrub sets the value for the Scrub field.
+func (opts *Options) SetScrub(policy ScrubPolicy) *Options {
+	opts.Scrub = policy
+	return opts
+}
+
 // Convert image according options opts.
 func (opts *Options) Convert(w io.Writer, base image.Image) error {
+	return opts.ConvertWithMetadata(w, base, nil)
+}
+
+// ConvertWithMetadata converts image according options opts, and writes the metadata md
+// of the kinds selected by the Metadata field into the output after scrubbing it
+// according to the Scrub field.
+func (opts *Options) ConvertWithMetadata(w io.Writer, base image.Image, md *Metadata) error {
+	if opts.Deskew != nil {
+		base = opts.Deskew.do(base)
+	}
+	if opts.Trim != nil {
+		base = opts.Trim.do(base)
+	}
+	if opts.Crop != nil {
+		base = opts.Crop.do(base)
+	}
+	if opts.Flip != FlipNone {
+		base = Flip(base, opts.Flip)
+	}
+	if opts.Rotate != nil {
+		base = opts.Rotate.do(base)
+	}
 	if opts.Gray {
-		base = ToGray(base)
+		base = Grayscale(base, opts.GrayOption)
 	}
 	if opts.Resize != nil {
 		base = opts.Resize.do(base)
 	}
+	if opts.Levels != nil {
+		base = opts.Levels.do(base)
+	}
+	if opts.Equalize {
+		base = Equalize(base)
+	}
+	if opts.CLAHE != nil {
+		base = opts.CLAHE.do(base)
+	}
+	if opts.Adjust != nil {
+		base = opts.Adjust.do(base)
+	}
+	for _, effect := range opts.Effect {
+		base = effect.do(base)
+	}
+	if opts.Blur > 0 {
+		base = GaussianBlur(base, opts.Blur)
+	}
+	if opts.Sharpen != nil {
+		base = opts.Sharpen.do(base)
+	}
+	if opts.Vignette != nil {
+		base = opts.Vignette.do(base)
+	}
+	if opts.Pad != nil {
+		base = opts.Pad.do(base)
+	}
 	if opts.Watermark != nil {
 		base = opts.Watermark.do(base)
 	}
+	if opts.Mask != nil {
+		base = opts.Mask.do(base)
+	}
+	if opts.Threshold != nil {
+		base = opts.Threshold.do(base)
+	}
 
 	if opts.Format == nil {
 		opts.Format = defaultFormat
 	}
 
-	return opts.Format.Encode(w, base)
+	format := opts.Format
+	if md != nil && opts.Metadata != NoMetadata {
+		md = md.Scrub(opts.Scrub).Select(opts.Metadata)
+		var options []EncodeOption
+		if md.DPI > 0 {
+			// A DPI set by the format options takes precedence.
+			options = append(options, DPI(md.DPI))
+		}
+		format = &FormatOption{
+			Format:       format.Format,
+			EncodeOption: append(append(options, format.EncodeOption...), EmbedMetadata(md)),
+		}
+	}
+
+	return format.Encode(w, base)
 }
 
 // ConvertExt convert filename's ext according image format.
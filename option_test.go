@@ -3,6 +3,7 @@ package imgconv
 import (
 	"bytes"
 	"image"
+	"image/color"
 	"io"
 	"testing"
 )
@@ -35,10 +36,62 @@ func TestOption(t *testing.T) {
 	if opts.Resize.Width != 0 || opts.Resize.Height != 0 || opts.Resize.Percent != 33 {
 		t.Fatal("SetResize result is not expect one.")
 	}
+	opts.SetCrop(3, 2, Bottom)
+	if opts.Crop.Width != 3 || opts.Crop.Height != 2 || opts.Crop.Anchor != Bottom {
+		t.Fatal("SetCrop result is not expect one.")
+	}
+	opts.SetDeskew(5, color.White)
+	if opts.Deskew.MaxAngle != 5 || opts.Deskew.Background != color.White {
+		t.Fatal("SetDeskew result is not expect one.")
+	}
+	opts.SetTrim(8, 2)
+	if *opts.Trim != (TrimOption{8, 2}) {
+		t.Fatal("SetTrim result is not expect one.")
+	}
+	opts.SetFlip(FlipVertical).SetRotate(30, nil).Rotate.SetCrop(true)
+	if opts.Flip != FlipVertical || opts.Rotate.Angle != 30 || !opts.Rotate.Crop {
+		t.Fatal("SetFlip or SetRotate result is not expect one.")
+	}
+	opts.SetAdjust(AdjustOption{Brightness: 10, Gamma: 1.2})
+	if opts.Adjust.Brightness != 10 || opts.Adjust.Gamma != 1.2 {
+		t.Fatal("SetAdjust result is not expect one.")
+	}
+	opts.SetEffect(EffectOption{Posterize: 4}, EffectOption{Sepia: true})
+	if len(opts.Effect) != 2 || opts.Effect[0].Posterize != 4 || !opts.Effect[1].Sepia {
+		t.Fatal("SetEffect result is not expect one.")
+	}
+	opts.SetBlur(0.5).SetSharpen(1, 0.8, 2)
+	if opts.Blur != 0.5 || *opts.Sharpen != (SharpenOption{1, 0.8, 2}) {
+		t.Fatal("SetBlur or SetSharpen result is not expect one.")
+	}
+	opts.SetAutoLevels(0.5, true).SetEqualize(true).SetCLAHE(4, 3)
+	if *opts.Levels != (AutoLevelsOption{0.5, true}) || !opts.Equalize || *opts.CLAHE != (CLAHEOption{4, 3}) {
+		t.Fatal("SetAutoLevels, SetEqualize or SetCLAHE result is not expect one.")
+	}
+	opts.SetPad(1, 2, 3, 4, nil).Pad.SetAspect(1, Top)
+	if opts.Pad.Top != 1 || opts.Pad.Left != 4 || opts.Pad.Aspect != 1 || opts.Pad.Anchor != Top {
+		t.Fatal("SetPad result is not expect one.")
+	}
+	opts.SetVignette(40, 0.6).SetRoundCorners(3)
+	if *opts.Vignette != (VignetteOption{40, 0.6}) || *opts.Mask != (MaskOption{CornerRadius: 3}) {
+		t.Fatal("SetVignette or SetRoundCorners result is not expect one.")
+	}
+	opts.SetCircleMask()
+	if !opts.Mask.Circle {
+		t.Fatal("SetCircleMask result is not expect one.")
+	}
+	opts.SetThreshold(ThresholdFixed, 100)
+	if *opts.Threshold != (ThresholdOption{Method: ThresholdFixed, Level: 100}) {
+		t.Fatal("SetThreshold result is not expect one.")
+	}
 	opts.SetGray(true)
 	if !opts.Gray {
 		t.Fatal("SetGray result is not expect one.")
 	}
+	opts.SetGrayMode(GrayRec709, true)
+	if !opts.Gray || *opts.GrayOption != (GrayOption{GrayRec709, true}) {
+		t.Fatal("SetGrayMode result is not expect one.")
+	}
 	if err := opts.Convert(io.Discard, mark); err != nil {
 		t.Fatal("Failed to Convert.")
 	}
@@ -0,0 +1,75 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// PadOption is padding option
+type PadOption struct {
+	Top, Right, Bottom, Left int
+	// Aspect is the width to height ratio the image is padded to after adding
+	// the margins, e.g. 1 for a square. It is ignored if 0.
+	Aspect float64
+	// Anchor is the position of the image on the canvas padded to Aspect.
+	Anchor Anchor
+	// Background is the padding color, transparent if nil. The padding can also
+	// be filled when encoding with the BackgroundColor option.
+	Background color.Color
+}
+
+// SetAspect sets the option for the Pad to pad the image to the aspect ratio.
+func (p *PadOption) SetAspect(ratio float64, anchor Anchor) *PadOption {
+	p.Aspect = ratio
+	p.Anchor = anchor
+	return p
+}
+
+func (p *PadOption) do(base image.Image) image.Image {
+	if p.Top > 0 || p.Right > 0 || p.Bottom > 0 || p.Left > 0 {
+		base = Pad(base, p.Top, p.Right, p.Bottom, p.Left, p.Background)
+	}
+	if p.Aspect > 0 {
+		base = PadToAspect(base, p.Aspect, p.Anchor, p.Background)
+	}
+	return base
+}
+
+// extend draws the image over a width x height canvas of bgColor at rect, so
+// that the background shows through the transparent pixels of the image.
+func extend(img image.Image, width, height int, rect image.Rectangle, bgColor color.Color) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if bgColor != nil {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(bgColor), image.Point{}, draw.Src)
+	}
+	draw.Draw(dst, rect, img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// Pad extends the canvas of the image by the margins in pixels on each side,
+// filled with bgColor, transparent if nil. The background also shows through the
+// transparent pixels of the image. Negative margins are ignored.
+func Pad(img image.Image, top, right, bottom, left int, bgColor color.Color) image.Image {
+	top, right, bottom, left = max(top, 0), max(right, 0), max(bottom, 0), max(left, 0)
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return extend(img, w+left+right, h+top+bottom, image.Rect(left, top, left+w, top+h), bgColor)
+}
+
+// PadToAspect extends the canvas of the image to the width to height ratio, such
+// as 1 for a square or 16.0/9 for a wide screen, and places the image on it using
+// the anchor point. The padding is filled with bgColor, transparent if nil.
+func PadToAspect(img image.Image, ratio float64, anchor Anchor, bgColor color.Color) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if ratio <= 0 || w == 0 || h == 0 {
+		return clone(img)
+	}
+	width, height := w, h
+	if float64(w)/float64(h) < ratio {
+		width = max(w, int(math.Round(float64(h)*ratio)))
+	} else {
+		height = max(h, int(math.Round(float64(w)/ratio)))
+	}
+	return extend(img, width, height, anchorRect(image.Rect(0, 0, width, height), w, h, anchor), bgColor)
+}
@@ -0,0 +1,73 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPad(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(5, 5, 9, 7))
+	for i := range src.Pix {
+		src.Pix[i] = 0x80
+	}
+
+	img := Pad(src, 1, 2, 3, 4, color.White).(*image.NRGBA)
+	if b := img.Bounds(); b != image.Rect(0, 0, 10, 6) {
+		t.Errorf("want 10x6, got %v", b)
+	}
+	if c := img.NRGBAAt(3, 1); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("want white padding, got %v", c)
+	}
+	// The background shows through the transparent pixels.
+	for _, p := range []image.Point{{4, 1}, {7, 2}} {
+		if c := img.NRGBAAt(p.X, p.Y); c.A != 255 || c.R < 0xbe || c.R > 0xc0 || c.R != c.B {
+			t.Errorf("want image over white, got %v", c)
+		}
+	}
+
+	img = Pad(src, -1, 0, 1, 0, nil).(*image.NRGBA)
+	if b := img.Bounds(); b != image.Rect(0, 0, 4, 3) {
+		t.Errorf("want 4x3, got %v", b)
+	}
+	if c := img.NRGBAAt(0, 2); c != (color.NRGBA{}) {
+		t.Errorf("want transparent padding, got %v", c)
+	}
+	if c := img.NRGBAAt(0, 0); c != (color.NRGBA{0x80, 0x80, 0x80, 0x80}) {
+		t.Errorf("want image kept unchanged, got %v", c)
+	}
+}
+
+func TestPadToAspect(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for i := range src.Pix {
+		src.Pix[i] = 0xff
+	}
+	testCases := []struct {
+		ratio  float64
+		anchor Anchor
+		size   image.Point
+		at     image.Point
+	}{
+		{1, Center, image.Pt(4, 4), image.Pt(0, 1)},
+		{1, Top, image.Pt(4, 4), image.Pt(0, 0)},
+		{1, Bottom, image.Pt(4, 4), image.Pt(0, 2)},
+		{4, Right, image.Pt(8, 2), image.Pt(4, 0)},
+		{2, Center, image.Pt(4, 2), image.Pt(0, 0)},
+	}
+	for _, tc := range testCases {
+		img := PadToAspect(src, tc.ratio, tc.anchor, color.Black).(*image.NRGBA)
+		if size := img.Bounds().Size(); size != tc.size {
+			t.Errorf("%g: want %v, got %v", tc.ratio, tc.size, size)
+			continue
+		}
+		if c := img.NRGBAAt(tc.at.X, tc.at.Y); c.R != 0xff {
+			t.Errorf("%g: want image at %v, got %v", tc.ratio, tc.at, c)
+		}
+	}
+
+	img := (&PadOption{Left: 2, Aspect: 1, Background: color.Black}).do(src)
+	if b := img.Bounds(); b != image.Rect(0, 0, 6, 6) {
+		t.Errorf("want 6x6, got %v", b)
+	}
+}
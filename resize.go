@@ -1,12 +1,147 @@
 package imgconv
 
-import "image"
+import (
+	"encoding"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+var (
+	_ encoding.TextUnmarshaler = new(ResizeMode)
+	_ encoding.TextMarshaler   = ResizeMode(0)
+	_ encoding.TextUnmarshaler = new(ResampleFilter)
+	_ encoding.TextMarshaler   = ResampleFilter(0)
+)
+
+// ResampleFilter is a resampling filter used to resize images.
+type ResampleFilter int
+
+const (
+	// Lanczos is a high-quality filter with sharp results (Lanczos3).
+	Lanczos ResampleFilter = iota
+	// NearestNeighbor is the fastest filter, without anti-aliasing. It keeps the
+	// hard edges of pixel art.
+	NearestNeighbor
+	// Box averages the source pixels, fast and suitable for downscaling.
+	Box
+	// Linear is a bilinear filter, smooth and reasonably fast.
+	Linear
+	// Hermite is a cubic spline filter (B=0, C=0).
+	Hermite
+	// CatmullRom is a sharp cubic filter (B=0, C=0.5).
+	CatmullRom
+	// MitchellNetravali is a smooth cubic filter (B=1/3, C=1/3).
+	MitchellNetravali
+	// Gaussian is a blurring filter.
+	Gaussian
+)
+
+var resampleFilters = []struct {
+	name   string
+	filter resampleFilter
+}{
+	{"lanczos", lanczos},
+	{"nearest", nearestNeighbor},
+	{"box", box},
+	{"linear", linear},
+	{"hermite", hermite},
+	{"catmull-rom", catmullRom},
+	{"mitchell", mitchellNetravali},
+	{"gaussian", gaussian},
+}
+
+func (f *ResampleFilter) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	for index, filter := range resampleFilters {
+		if s == filter.name {
+			*f = ResampleFilter(index)
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported resample filter: %s", s)
+}
+
+func (f ResampleFilter) MarshalText() ([]byte, error) {
+	if f < 0 || int(f) >= len(resampleFilters) {
+		return []byte("unknown"), nil
+	}
+	return []byte(resampleFilters[f].name), nil
+}
+
+func (f ResampleFilter) filter() resampleFilter {
+	if f < 0 || int(f) >= len(resampleFilters) {
+		return lanczos
+	}
+	return resampleFilters[f].filter
+}
+
+// ResizeMode defines how the image is resized when both Width and Height are set.
+// If only one of them is set, the image aspect ratio is always preserved.
+type ResizeMode int
+
+const (
+	// ResizeExact stretches the image to Width x Height.
+	ResizeExact ResizeMode = iota
+	// ResizeFit scales the image to fit inside Width x Height, preserving the
+	// aspect ratio.
+	ResizeFit
+	// ResizeFill scales the image to cover Width x Height, preserving the aspect
+	// ratio, and crops the overflow using Anchor.
+	ResizeFill
+	// ResizePad scales the image to fit inside Width x Height, preserving the
+	// aspect ratio, and places it at Anchor on a Width x Height canvas filled with
+	// Background.
+	ResizePad
+)
+
+var resizeModes = []string{
+	"exact",
+	"fit",
+	"fill",
+	"pad",
+}
+
+func (m *ResizeMode) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	for index, mode := range resizeModes {
+		if s == mode {
+			*m = ResizeMode(index)
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported resize mode: %s", s)
+}
+
+func (m ResizeMode) MarshalText() ([]byte, error) {
+	if m < 0 || int(m) >= len(resizeModes) {
+		return []byte("unknown"), nil
+	}
+	return []byte(resizeModes[m]), nil
+}
 
 // ResizeOption is resize option
 type ResizeOption struct {
 	Width   int
 	Height  int
 	Percent float64
+
+	Mode ResizeMode
+	// Anchor is used to crop in ResizeFill mode and to place the image in
+	// ResizePad mode.
+	Anchor Anchor
+	// Background is the padding color in ResizePad mode, transparent if nil.
+	Background color.Color
+	// NoUpscale prevents the image from being enlarged. In ResizePad mode, the
+	// image is still padded to Width x Height.
+	NoUpscale bool
+	// Filter is the resampling filter, Lanczos by default.
+	Filter ResampleFilter
+	// LinearLight resamples in linear light instead of sRGB-encoded values, which
+	// keeps the brightness of fine details and high-contrast edges when downscaling.
+	LinearLight bool
 }
 
 // Resize resizes image
@@ -14,10 +149,87 @@ func Resize(base image.Image, option *ResizeOption) image.Image {
 	return option.do(base)
 }
 
+// SetMode sets the option for the Resize mode and anchor.
+func (r *ResizeOption) SetMode(mode ResizeMode, anchor Anchor) *ResizeOption {
+	r.Mode = mode
+	r.Anchor = anchor
+	return r
+}
+
+// SetFilter sets the option for the Resize resampling filter.
+func (r *ResizeOption) SetFilter(filter ResampleFilter) *ResizeOption {
+	r.Filter = filter
+	return r
+}
+
+// SetLinearLight sets the option for the Resize to resample in linear light or not.
+func (r *ResizeOption) SetLinearLight(linearLight bool) *ResizeOption {
+	r.LinearLight = linearLight
+	return r
+}
+
+// SetBackground sets the option for the Resize padding color.
+func (r *ResizeOption) SetBackground(c color.Color) *ResizeOption {
+	r.Background = c
+	return r
+}
+
+// SetNoUpscale sets the option for the Resize to never enlarge the image or not.
+func (r *ResizeOption) SetNoUpscale(noUpscale bool) *ResizeOption {
+	r.NoUpscale = noUpscale
+	return r
+}
+
 func (r *ResizeOption) do(base image.Image) image.Image {
+	filter := r.Filter.filter()
+	resizeFunc := resize
+	if r.LinearLight {
+		resizeFunc = resizeLinear
+	}
+	b := base.Bounds()
 	if r.Width == 0 && r.Height == 0 {
-		return resize(base, int(float64(base.Bounds().Dx())*r.Percent/100), 0, lanczos)
+		percent := r.Percent
+		if r.NoUpscale {
+			percent = min(percent, 100)
+		}
+		return resizeFunc(base, int(float64(b.Dx())*percent/100), 0, filter)
+	}
+
+	if r.Mode == ResizeExact || r.Width == 0 || r.Height == 0 {
+		width, height := r.Width, r.Height
+		if r.NoUpscale {
+			// Scale both sides by the same factor to keep the aspect ratio.
+			scale := 1.0
+			if width > 0 {
+				scale = min(scale, float64(b.Dx())/float64(width))
+			}
+			if height > 0 {
+				scale = min(scale, float64(b.Dy())/float64(height))
+			}
+			if scale < 1 {
+				width = int(math.Round(float64(width) * scale))
+				height = int(math.Round(float64(height) * scale))
+			}
+		}
+		return resizeFunc(base, width, height, filter)
 	}
 
-	return resize(base, r.Width, r.Height, lanczos)
+	scale := math.Min(float64(r.Width)/float64(b.Dx()), float64(r.Height)/float64(b.Dy()))
+	if r.Mode == ResizeFill {
+		scale = math.Max(float64(r.Width)/float64(b.Dx()), float64(r.Height)/float64(b.Dy()))
+	}
+	if r.NoUpscale {
+		scale = min(scale, 1)
+	}
+	width := max(1, int(math.Round(float64(b.Dx())*scale)))
+	height := max(1, int(math.Round(float64(b.Dy())*scale)))
+	img := resizeFunc(base, width, height, filter)
+
+	switch r.Mode {
+	case ResizeFill:
+		return CropAnchor(img, r.Width, r.Height, r.Anchor)
+	case ResizePad:
+		return extend(img, r.Width, r.Height, anchorRect(image.Rect(0, 0, r.Width, r.Height), width, height, r.Anchor), r.Background)
+	}
+	return img
 }
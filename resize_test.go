@@ -2,6 +2,7 @@ package imgconv
 
 import (
 	"image"
+	"image/color"
 	"testing"
 )
 
@@ -36,6 +37,16 @@ func TestResize(t *testing.T) {
 		{&ResizeOption{Height: 206}, image.Pt(300, 206)},
 		{&ResizeOption{Width: 200, Height: 200}, image.Pt(200, 200)},
 		{&ResizeOption{Percent: 50}, image.Pt(75, 52)},
+		{&ResizeOption{Width: 200, Height: 200, Mode: ResizeFit}, image.Pt(200, 137)},
+		{&ResizeOption{Width: 200, Height: 200, Mode: ResizeFill}, image.Pt(200, 200)},
+		{&ResizeOption{Width: 200, Height: 200, Mode: ResizePad}, image.Pt(200, 200)},
+		{&ResizeOption{Width: 300, NoUpscale: true}, image.Pt(150, 103)},
+		{&ResizeOption{Percent: 200, NoUpscale: true}, image.Pt(150, 103)},
+		{&ResizeOption{Width: 300, Height: 103, NoUpscale: true}, image.Pt(150, 52)},
+		{&ResizeOption{Width: 100, Height: 50, NoUpscale: true}, image.Pt(100, 50)},
+		{&ResizeOption{Width: 300, Height: 300, Mode: ResizeFit, NoUpscale: true}, image.Pt(150, 103)},
+		{&ResizeOption{Width: 100, Height: 200, Mode: ResizeFill, NoUpscale: true}, image.Pt(100, 103)},
+		{&ResizeOption{Width: 300, Height: 300, Mode: ResizePad, NoUpscale: true}, image.Pt(300, 300)},
 	}
 
 	// Read the image.
@@ -55,3 +66,67 @@ func TestResize(t *testing.T) {
 		compare(t, img0, img1)
 	}
 }
+
+func TestResizeMode(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for x := range 4 {
+		src.SetNRGBA(x, 0, color.NRGBA{uint8(x * 60), 0, 0, 255})
+		src.SetNRGBA(x, 1, color.NRGBA{uint8(x * 60), 0, 0, 255})
+	}
+
+	img := Resize(src, &ResizeOption{Width: 2, Height: 2, Mode: ResizeFill, Anchor: Left})
+	compare(t, src.SubImage(image.Rect(0, 0, 2, 2)), img)
+
+	img = Resize(src, (&ResizeOption{Width: 4, Height: 4}).SetMode(ResizePad, Bottom).SetBackground(color.White))
+	white := image.NewNRGBA(src.Rect)
+	for i := range white.Pix {
+		white.Pix[i] = 0xff
+	}
+	compare(t, white, img.(*image.NRGBA).SubImage(image.Rect(0, 0, 4, 2)))
+	compare(t, src, img.(*image.NRGBA).SubImage(image.Rect(0, 2, 4, 4)))
+
+	img = Resize(src, &ResizeOption{Width: 4, Height: 4, Mode: ResizePad})
+	if c := img.At(0, 0); c != (color.NRGBA{}) {
+		t.Errorf("want transparent padding, got %v", c)
+	}
+
+	var m ResizeMode
+	if err := m.UnmarshalText([]byte("Fill")); err != nil || m != ResizeFill {
+		t.Errorf("want fill mode, got %v, %v", m, err)
+	}
+	if err := m.UnmarshalText([]byte("cover")); err == nil {
+		t.Error("want error for unknown resize mode")
+	}
+}
+
+func TestResampleFilter(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	src.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	src.SetNRGBA(1, 0, color.NRGBA{0, 255, 0, 255})
+	src.SetNRGBA(0, 1, color.NRGBA{0, 0, 255, 255})
+	src.SetNRGBA(1, 1, color.NRGBA{255, 255, 255, 255})
+
+	img := Resize(src, (&ResizeOption{Width: 6, Height: 6}).SetFilter(NearestNeighbor))
+	for y := range 6 {
+		for x := range 6 {
+			if want, got := src.At(x/3, y/3), img.At(x, y); want != got {
+				t.Fatalf("want %v at (%d, %d), got %v", want, x, y, got)
+			}
+		}
+	}
+
+	for i := range resampleFilters {
+		img := Resize(src, &ResizeOption{Width: 1, Height: 1, Filter: ResampleFilter(i)})
+		if img.Bounds().Dx() != 1 || img.Bounds().Dy() != 1 {
+			t.Errorf("%s: want 1x1 image, got %v", resampleFilters[i].name, img.Bounds())
+		}
+		b, _ := ResampleFilter(i).MarshalText()
+		var f ResampleFilter
+		if err := f.UnmarshalText(b); err != nil || f != ResampleFilter(i) {
+			t.Errorf("want %s, got %v, %v", b, f, err)
+		}
+	}
+	if c := Resize(src, &ResizeOption{Width: 1, Height: 1, Filter: Box}).(*image.NRGBA).NRGBAAt(0, 0); c != (color.NRGBA{128, 128, 128, 255}) {
+		t.Errorf("want average color, got %v", c)
+	}
+}
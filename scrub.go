@@ -0,0 +1,363 @@
+package imgconv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ encoding.TextUnmarshaler = new(ScrubMode)
+	_ encoding.TextMarshaler   = ScrubMode(0)
+	_ encoding.TextUnmarshaler = new(EXIFTags)
+	_ encoding.TextMarshaler   = EXIFTags(nil)
+)
+
+// ScrubMode defines which metadata is removed by a ScrubPolicy.
+// The ICC profile is always kept, as it holds no personal data and is needed
+// to display the colors correctly.
+type ScrubMode int
+
+const (
+	// ScrubNone keeps all metadata.
+	ScrubNone ScrubMode = iota
+	// ScrubAll removes all EXIF and XMP metadata except the orientation tag.
+	ScrubAll
+	// ScrubGPS removes the GPS location from the EXIF metadata, and the XMP
+	// metadata if it mentions GPS properties.
+	ScrubGPS
+	// ScrubAllowlist keeps only the EXIF tags of the IFD0 and Exif IFD listed in the
+	// policy, in addition to the orientation tag. XMP metadata is removed.
+	ScrubAllowlist
+)
+
+var scrubModes = []string{
+	"none",
+	"all",
+	"gps",
+	"allowlist",
+}
+
+func (m *ScrubMode) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	for index, mode := range scrubModes {
+		if s == mode {
+			*m = ScrubMode(index)
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported scrub mode: %s", s)
+}
+
+func (m ScrubMode) MarshalText() ([]byte, error) {
+	if m < 0 || int(m) >= len(scrubModes) {
+		return []byte("unknown"), nil
+	}
+	return []byte(scrubModes[m]), nil
+}
+
+// EXIFTags is a list of EXIF tag IDs.
+type EXIFTags []uint16
+
+var exifTagNames = map[string]uint16{
+	"ImageWidth":            tagImageWidth,
+	"ImageLength":           tagImageLength,
+	"ImageDescription":      0x010e,
+	"Make":                  tagMake,
+	"Model":                 tagModel,
+	"Orientation":           tagOrientation,
+	"XResolution":           tagXResolution,
+	"YResolution":           tagYResolution,
+	"ResolutionUnit":        tagResolutionUnit,
+	"Software":              tagSoftware,
+	"DateTime":              tagDateTime,
+	"Artist":                0x013b,
+	"Copyright":             0x8298,
+	"ExposureTime":          tagExposureTime,
+	"FNumber":               tagFNumber,
+	"ExposureProgram":       0x8822,
+	"ISOSpeed":              tagISOSpeed,
+	"DateTimeOriginal":      tagDateTimeOriginal,
+	"DateTimeDigitized":     0x9004,
+	"OffsetTime":            0x9010,
+	"OffsetTimeOriginal":    tagOffsetTimeOriginal,
+	"ExposureBiasValue":     0x9204,
+	"MeteringMode":          0x9207,
+	"Flash":                 0x9209,
+	"FocalLength":           tagFocalLength,
+	"MakerNote":             0x927c,
+	"UserComment":           0x9286,
+	"ColorSpace":            0xa001,
+	"PixelXDimension":       tagPixelXDimension,
+	"PixelYDimension":       tagPixelYDimension,
+	"WhiteBalance":          0xa403,
+	"FocalLengthIn35mmFilm": tagFocalLengthIn35mmFilm,
+	"CameraOwnerName":       0xa430,
+	"BodySerialNumber":      0xa431,
+	"LensMake":              tagLensMake,
+	"LensModel":             tagLensModel,
+	"LensSerialNumber":      0xa435,
+}
+
+// UnmarshalText parses a comma-separated list of EXIF tags. A tag is either given
+// by name, such as "Make" or "Copyright", or by number, such as "0x8298".
+func (t *EXIFTags) UnmarshalText(text []byte) error {
+	var tags EXIFTags
+	for s := range strings.SplitSeq(string(text), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if tag, ok := exifTagNames[s]; ok {
+			tags = append(tags, tag)
+			continue
+		}
+		tag, err := strconv.ParseUint(s, 0, 16)
+		if err != nil {
+			return fmt.Errorf("unknown exif tag: %s", s)
+		}
+		tags = append(tags, uint16(tag))
+	}
+	*t = tags
+	return nil
+}
+
+func (t EXIFTags) MarshalText() ([]byte, error) {
+	var names []string
+	for _, tag := range t {
+		name := fmt.Sprintf("0x%04x", tag)
+		for k, v := range exifTagNames {
+			if v == tag {
+				name = k
+				break
+			}
+		}
+		names = append(names, name)
+	}
+	return []byte(strings.Join(names, ",")), nil
+}
+
+// ScrubPolicy describes the metadata to remove from an image.
+type ScrubPolicy struct {
+	Mode ScrubMode
+	// Allow lists the EXIF tags kept in ScrubAllowlist mode.
+	Allow EXIFTags
+}
+
+// scrubEXIF returns the EXIF fields kept by the policy, or nil if none is kept.
+func (p ScrubPolicy) scrubEXIF(d *exifData) *exifData {
+	if d == nil {
+		return nil
+	}
+	res := &exifData{order: d.order}
+	switch p.Mode {
+	case ScrubNone:
+		return d
+	case ScrubGPS:
+		res.ifd0, res.exif, res.interop = d.ifd0, d.exif, d.interop
+	case ScrubAll:
+		if e, ok := d.ifd0.find(tagOrientation); ok {
+			res.ifd0 = exifIFD{e}
+		}
+	case ScrubAllowlist:
+		keep := func(e exifEntry) bool { return !slices.Contains(p.Allow, e.tag) }
+		res.ifd0 = slices.DeleteFunc(slices.Clone(d.ifd0), func(e exifEntry) bool {
+			return e.tag != tagOrientation && keep(e)
+		})
+		res.exif = slices.DeleteFunc(slices.Clone(d.exif), keep)
+	}
+	if len(res.ifd0) == 0 && len(res.exif) == 0 {
+		return nil
+	}
+	return res
+}
+
+// scrubXMP returns the XMP packet if it is kept by the policy.
+func (p ScrubPolicy) scrubXMP(xmp []byte) []byte {
+	switch p.Mode {
+	case ScrubNone:
+		return xmp
+	case ScrubGPS:
+		if !bytes.Contains(xmp, []byte("GPS")) {
+			return xmp
+		}
+	}
+	return nil
+}
+
+// Scrub returns a copy of md with the metadata removed according to the policy.
+func (md *Metadata) Scrub(policy ScrubPolicy) *Metadata {
+	if md == nil || policy.Mode == ScrubNone {
+		return md
+	}
+	res := &Metadata{XMP: policy.scrubXMP(md.XMP), ICC: md.ICC, DPI: md.DPI}
+	if md.EXIF != nil {
+		if d := policy.scrubEXIF(md.EXIF.data); d != nil {
+			res.EXIF = exifFromData(d)
+			res.EXIF.Orientation = md.EXIF.Orientation
+		}
+	}
+	return res
+}
+
+// ScrubJPEG copies the JPEG image from r to w, removing metadata according to the
+// policy. The image data is copied without re-encoding. Unless the mode is
+// ScrubNone, data after the end of the image, such as appended preview images,
+// is dropped along with the MPF segment describing it. Only the EXIF, XMP and
+// extended XMP segments left by the policy, the ICC profile and the JFIF and
+// Adobe segments are kept, other application segments and comments are removed.
+func ScrubJPEG(w io.Writer, r io.Reader, policy ScrubPolicy) error {
+	if policy.Mode == ScrubNone {
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil || !bytes.Equal(soi[:], jpegMagic) {
+		return errors.New("jpeg: missing SOI marker")
+	}
+	bw.Write(soi[:])
+
+	errInvalid := errors.New("jpeg: invalid format")
+	var scan bool
+	var ext extendedXMP
+	for {
+		var marker uint16
+		if !scan {
+			if err := binary.Read(br, binary.BigEndian, &marker); err != nil {
+				return err
+			}
+			if marker>>8 != 0xff {
+				return errInvalid
+			}
+		} else {
+			// Copy entropy-coded data up to the next marker.
+			c, err := br.ReadByte()
+			if err != nil {
+				return err
+			}
+			if c != 0xff {
+				bw.WriteByte(c)
+				continue
+			}
+			next, err := br.ReadByte()
+			if err != nil {
+				return err
+			}
+			switch {
+			case next == 0x00 || next >= 0xd0 && next <= 0xd7:
+				// Byte stuffing or restart marker.
+				bw.Write([]byte{c, next})
+				continue
+			case next == 0xff:
+				// Fill byte.
+				br.UnreadByte()
+				continue
+			}
+			marker = uint16(c)<<8 | uint16(next)
+		}
+
+		if marker == markerEOI {
+			bw.Write([]byte{0xff, 0xd9})
+			return bw.Flush()
+		}
+		var size uint16
+		if err := binary.Read(br, binary.BigEndian, &size); err != nil {
+			return err
+		}
+		if size < 2 {
+			return errInvalid
+		}
+		data := make([]byte, size-2)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return err
+		}
+		if marker == markerSOS {
+			scan = true
+		} else if data = policy.scrubSegment(marker, data, &ext); data == nil {
+			continue
+		}
+		if len(data) > maxJPEGSegmentSize {
+			continue
+		}
+		binary.Write(bw, binary.BigEndian, []uint16{marker, uint16(len(data) + 2)})
+		bw.Write(data)
+	}
+}
+
+// extendedXMP is the state of the extended XMP segments of a JPEG image.
+type extendedXMP struct {
+	// removed is set once the main XMP packet or a chunk is removed, the later
+	// chunks are removed too.
+	removed bool
+	// tail is the end of the previous chunk, so that properties split across
+	// chunks are found.
+	tail []byte
+}
+
+// scrub returns the extended XMP segment data if it is kept by the policy.
+func (x *extendedXMP) scrub(p ScrubPolicy, data []byte) []byte {
+	const chunkOffset = 32 + 4 + 4
+	if x.removed || len(data) < len(xmpExtHeader)+chunkOffset {
+		x.removed = true
+		return nil
+	}
+	chunk := data[len(xmpExtHeader)+chunkOffset:]
+	if p.scrubXMP(append(x.tail, chunk...)) == nil {
+		x.removed = true
+		return nil
+	}
+	x.tail = slices.Clone(chunk[max(len(chunk)-len("GPS")+1, 0):])
+	return data
+}
+
+// scrubSegment returns the data of a JPEG marker segment kept by the policy,
+// or nil if the segment is removed.
+func (p ScrubPolicy) scrubSegment(marker uint16, data []byte, ext *extendedXMP) []byte {
+	const (
+		markerAPP15 = 0xffef
+		markerCOM   = 0xfffe
+	)
+
+	switch {
+	case marker == markerAPP1 && bytes.HasPrefix(data, exifHeader):
+		d, err := parseExif(data[len(exifHeader):])
+		if err != nil {
+			return nil
+		}
+		if d = p.scrubEXIF(d); d == nil {
+			return nil
+		}
+		return append(slices.Clone(exifHeader), d.encode()...)
+	case marker == markerAPP1 && bytes.HasPrefix(data, xmpHeader):
+		if p.scrubXMP(data[len(xmpHeader):]) == nil {
+			ext.removed = true
+			return nil
+		}
+		return data
+	case marker == markerAPP1 && bytes.HasPrefix(data, xmpExtHeader):
+		return ext.scrub(p, data)
+	case marker == markerAPP2:
+		if bytes.HasPrefix(data, iccHeader) {
+			return data
+		}
+		return nil
+	case marker == markerAPP0 || marker == markerAPP14:
+		// JFIF and Adobe segments describe the image data.
+		return data
+	case marker >= markerAPP1 && marker <= markerAPP15 || marker == markerCOM:
+		// Other application segments and comments may hold any information,
+		// such as the XMP and IPTC metadata in the Photoshop APP13 segment.
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,162 @@
+package imgconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"image"
+	"image/jpeg"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestScrubJPEG(t *testing.T) {
+	x, err := newEXIF(sampleExif(binary.LittleEndian))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := &Metadata{EXIF: x, XMP: []byte(`<x:xmpmeta exif:GPSLatitude="31,12.6N"/>`), ICC: []byte("gray icc profileGRAY")}
+
+	var buf bytes.Buffer
+	if err := (&FormatOption{JPEG, []EncodeOption{EmbedMetadata(md)}}).Encode(&buf, image.NewGray(image.Rect(0, 0, 16, 8))); err != nil {
+		t.Fatal(err)
+	}
+	src := buf.Bytes()
+	sos := bytes.Index(src, []byte{0xff, 0xda})
+	src = append(src, "trailing"...)
+
+	for _, tc := range []struct {
+		policy ScrubPolicy
+		check  func(*Metadata) bool
+	}{
+		{ScrubPolicy{Mode: ScrubGPS}, func(md *Metadata) bool {
+			return md.EXIF != nil && md.EXIF.GPS == nil && md.EXIF.Model == "Canon EOS R5" && md.XMP == nil
+		}},
+		{ScrubPolicy{Mode: ScrubAll}, func(md *Metadata) bool {
+			return md.EXIF != nil && md.EXIF.Model == "" && md.EXIF.Orientation == 6 && md.XMP == nil
+		}},
+		{ScrubPolicy{Mode: ScrubAllowlist, Allow: EXIFTags{tagModel, tagFNumber}}, func(md *Metadata) bool {
+			return md.EXIF != nil && md.EXIF.Make == "" && md.EXIF.Model == "Canon EOS R5" &&
+				md.EXIF.FNumber == x.FNumber && md.EXIF.ISO == 0 && md.EXIF.GPS == nil && md.EXIF.Orientation == 6
+		}},
+	} {
+		var buf bytes.Buffer
+		if err := ScrubJPEG(&buf, bytes.NewReader(src), tc.policy); err != nil {
+			t.Fatal(tc.policy.Mode, err)
+		}
+		b := buf.Bytes()
+		res, err := ReadMetadata(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(tc.policy.Mode, err)
+		}
+		if !tc.check(res) {
+			t.Errorf("%v: wrong metadata: %+v %+v", tc.policy.Mode, res.EXIF, res)
+		}
+		if !bytes.Equal(res.ICC, md.ICC) {
+			t.Errorf("%v: want ICC profile kept, got %q", tc.policy.Mode, res.ICC)
+		}
+		if !bytes.Equal(b[bytes.Index(b, []byte{0xff, 0xda}):], src[sos:len(src)-len("trailing")]) {
+			t.Errorf("%v: image data changed", tc.policy.Mode)
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(b)); err != nil {
+			t.Errorf("%v: %v", tc.policy.Mode, err)
+		}
+	}
+
+	// Extended XMP chunks are checked for GPS properties, even if split across
+	// chunks, and Photoshop segments are removed.
+	segment := func(marker uint16, data ...[]byte) []byte {
+		b := bytes.Join(data, nil)
+		return append(binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(nil, marker), uint16(len(b)+2)), b...)
+	}
+	chunk := func(offset int, s string) []byte {
+		return segment(markerAPP1, xmpExtHeader, bytes.Repeat([]byte("0"), 32), []byte{0, 0, 0, 64, 0, 0, 0, byte(offset)}, []byte(s))
+	}
+	kept := chunk(0, `<rdf:Description exif:DateTimeOriginal="2024-05-01"/>`)
+	for _, tc := range []struct {
+		segments [][]byte
+		kept     []byte
+	}{
+		{[][]byte{kept}, kept},
+		{[][]byte{chunk(0, `<rdf:Description exif:G`), chunk(23, `PSLatitude="31,12.6N"/>`)}, chunk(0, `<rdf:Description exif:G`)},
+		{[][]byte{chunk(0, `<rdf:Description exif:GPSLatitude="31,12.6N"/>`), kept}, nil},
+		{[][]byte{segment(0xffed, []byte("Photoshop 3.0\x00"), []byte(`8BIM\x04\x24<x:xmpmeta exif:GPSLatitude="31,12.6N"/>`))}, nil},
+	} {
+		in := slices.Concat(src[:2], bytes.Join(tc.segments, nil), src[2:])
+		var buf bytes.Buffer
+		if err := ScrubJPEG(&buf, bytes.NewReader(in), ScrubPolicy{Mode: ScrubGPS}); err != nil {
+			t.Fatal(err)
+		}
+		if b := buf.Bytes(); bytes.Contains(b, []byte("31,12.6N")) || tc.kept != nil && !bytes.Contains(b, tc.kept) {
+			t.Errorf("wrong segments kept of %q", tc.segments)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := ScrubJPEG(&out, bytes.NewReader(src), ScrubPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), src) {
+		t.Error("want unchanged image with ScrubNone")
+	}
+	if err := ScrubJPEG(io.Discard, bytes.NewBufferString("Hello"), ScrubPolicy{Mode: ScrubAll}); err == nil {
+		t.Error("want error for invalid jpeg")
+	}
+}
+
+func TestMetadataScrub(t *testing.T) {
+	x, err := newEXIF(sampleExif(binary.BigEndian))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.Orientation = 1
+	md := &Metadata{EXIF: x, XMP: []byte("<x:xmpmeta/>")}
+
+	if res := md.Scrub(ScrubPolicy{Mode: ScrubGPS}); res.EXIF.GPS != nil || res.EXIF.Orientation != 1 || res.XMP == nil {
+		t.Errorf("wrong scrubbed metadata: %+v", res.EXIF)
+	}
+	if md.EXIF.GPS == nil {
+		t.Error("original metadata changed")
+	}
+
+	var buf bytes.Buffer
+	if err := NewOptions().SetMetadata(AllMetadata).SetScrub(ScrubPolicy{Mode: ScrubAll}).
+		ConvertWithMetadata(&buf, image.NewGray(image.Rect(0, 0, 2, 2)), md); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ReadMetadata(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EXIF == nil || res.EXIF.Make != "" || res.EXIF.GPS != nil || res.XMP != nil {
+		t.Errorf("wrong converted metadata: %+v", res.EXIF)
+	}
+}
+
+func TestScrubFlags(t *testing.T) {
+	f := flag.NewFlagSet("test", flag.ContinueOnError)
+	f.SetOutput(io.Discard)
+	var mode ScrubMode
+	var tags EXIFTags
+	f.TextVar(&mode, "scrub", ScrubNone, "")
+	f.TextVar(&tags, "keep", EXIFTags(nil), "")
+	if err := f.Parse([]string{"-scrub", "AllowList", "-keep", "Make, Copyright,0x9999"}); err != nil {
+		t.Fatal(err)
+	}
+	if mode != ScrubAllowlist {
+		t.Errorf("expected allowlist mode; got %d", mode)
+	}
+	if want := (EXIFTags{tagMake, 0x8298, 0x9999}); !slices.Equal(tags, want) {
+		t.Errorf("expected %v; got %v", want, tags)
+	}
+	if text, _ := tags.MarshalText(); string(text) != "Make,Copyright,0x9999" {
+		t.Errorf("expected %q; got %q", "Make,Copyright,0x9999", text)
+	}
+	if err := f.Parse([]string{"-keep", "Unknown"}); err == nil {
+		t.Error("want error for unknown tag")
+	}
+	if err := f.Parse([]string{"-scrub", "location"}); err == nil {
+		t.Error("want error for unknown mode")
+	}
+}
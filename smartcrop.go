@@ -0,0 +1,213 @@
+package imgconv
+
+import (
+	"image"
+	"math"
+)
+
+// Smart crop analysis parameters, following smartcrop.js.
+const (
+	smartCropAnalysisSize = 256 // longest side of the analysis image
+	smartCropScoreCell    = 4   // analysis pixels pooled per score cell
+	smartCropStep         = 8   // candidate offset step in analysis pixels
+
+	smartCropDetailWeight     = 0.2
+	smartCropSkinWeight       = 1.8
+	smartCropSkinBias         = 0.01
+	smartCropSkinThreshold    = 0.8
+	smartCropSkinBrightMin    = 0.2
+	smartCropSaturationWeight = 0.1
+	smartCropSaturationBias   = 0.2
+	smartCropSaturationThresh = 0.4
+	smartCropSaturationMin    = 0.05
+	smartCropSaturationMax    = 0.9
+	smartCropEdgeRadius       = 0.4
+	smartCropEdgeWeight       = -20
+	smartCropOutsideScore     = -0.5
+)
+
+var smartCropSkinColor = [3]float64{0.78, 0.57, 0.44}
+
+// smartCropFeatures holds the per cell features of the analysis image, each in [0, 1].
+type smartCropFeatures struct {
+	width, height            int // analysis image size
+	w, h                     int // number of cells
+	detail, skin, saturation []float64
+}
+
+// SmartCrop cuts out the most interesting region of the image with the aspect ratio
+// of width x height, and resizes it to width x height. Candidate regions are scored
+// by edge density, skin tone and saturation, weighted towards the center and the
+// rule of thirds lines of the region. It also returns the chosen region in the
+// coordinates of img.
+func SmartCrop(img image.Image, width, height int) (image.Image, image.Rectangle) {
+	b := img.Bounds()
+	if width <= 0 || height <= 0 || b.Empty() {
+		return &image.NRGBA{}, image.Rectangle{}
+	}
+
+	// The largest region of the requested aspect ratio fitting the image.
+	cropW, cropH := b.Dx(), int(math.Round(float64(b.Dx())*float64(height)/float64(width)))
+	if cropH > b.Dy() {
+		cropW, cropH = int(math.Round(float64(b.Dy())*float64(width)/float64(height))), b.Dy()
+	}
+	cropW, cropH = max(cropW, 1), max(cropH, 1)
+
+	rect := anchorRect(b, cropW, cropH, Center)
+	if cropW < b.Dx() || cropH < b.Dy() {
+		scale := min(1, float64(smartCropAnalysisSize)/float64(max(b.Dx(), b.Dy())))
+		analysis := resize(img, max(1, int(math.Round(float64(b.Dx())*scale))),
+			max(1, int(math.Round(float64(b.Dy())*scale))), lanczos)
+		sx := float64(b.Dx()) / float64(analysis.Rect.Dx())
+		sy := float64(b.Dy()) / float64(analysis.Rect.Dy())
+		best := newSmartCropFeatures(analysis).best(
+			min(int(float64(cropW)/sx), analysis.Rect.Dx()), min(int(float64(cropH)/sy), analysis.Rect.Dy()))
+		x := min(b.Min.X+int(math.Round(float64(best.X)*sx)), b.Max.X-cropW)
+		y := min(b.Min.Y+int(math.Round(float64(best.Y)*sy)), b.Max.Y-cropH)
+		rect = image.Rect(x, y, x+cropW, y+cropH)
+	}
+
+	res := Crop(img, rect)
+	if cropW != width || cropH != height {
+		res = resize(res, width, height, lanczos)
+	}
+	return res, rect
+}
+
+func newSmartCropFeatures(img *image.NRGBA) *smartCropFeatures {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	lum := make([]float64, w*h)
+	for y := range h {
+		for x := range w {
+			i := y*img.Stride + x*4
+			lum[y*w+x] = (0.2126*float64(img.Pix[i]) + 0.7152*float64(img.Pix[i+1]) + 0.0722*float64(img.Pix[i+2])) / 255
+		}
+	}
+
+	detail := make([]float64, w*h)
+	skin := make([]float64, w*h)
+	saturation := make([]float64, w*h)
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			for x := range w {
+				i := y*img.Stride + x*4
+				r, g, b := float64(img.Pix[i])/255, float64(img.Pix[i+1])/255, float64(img.Pix[i+2])/255
+				j := y*w + x
+				l := lum[j]
+
+				// Laplacian of the luminance, only the center on the borders.
+				d := l
+				if x > 0 && y > 0 && x < w-1 && y < h-1 {
+					d = 4*l - lum[j-w] - lum[j+w] - lum[j-1] - lum[j+1]
+				}
+				detail[j] = min(max(d, 0), 1)
+
+				if mag := math.Sqrt(r*r + g*g + b*b); mag > 0 && l >= smartCropSkinBrightMin {
+					dr, dg, db := r/mag-smartCropSkinColor[0], g/mag-smartCropSkinColor[1], b/mag-smartCropSkinColor[2]
+					if s := 1 - math.Sqrt(dr*dr+dg*dg+db*db); s > smartCropSkinThreshold {
+						skin[j] = (s - smartCropSkinThreshold) / (1 - smartCropSkinThreshold)
+					}
+				}
+
+				maxC, minC := max(r, g, b), min(r, g, b)
+				if lightness := (maxC + minC) / 2; maxC != minC && lightness >= smartCropSaturationMin && lightness <= smartCropSaturationMax {
+					s := (maxC - minC) / (maxC + minC)
+					if lightness > 0.5 {
+						s = (maxC - minC) / (2 - maxC - minC)
+					}
+					if s > smartCropSaturationThresh {
+						saturation[j] = (s - smartCropSaturationThresh) / (1 - smartCropSaturationThresh)
+					}
+				}
+			}
+		}
+	})
+
+	// Pool the maximum of each cell to keep the scoring fast.
+	f := &smartCropFeatures{
+		width: w, height: h,
+		w: (w + smartCropScoreCell - 1) / smartCropScoreCell, h: (h + smartCropScoreCell - 1) / smartCropScoreCell,
+	}
+	f.detail = make([]float64, f.w*f.h)
+	f.skin = make([]float64, f.w*f.h)
+	f.saturation = make([]float64, f.w*f.h)
+	for y := range h {
+		for x := range w {
+			i, j := (y/smartCropScoreCell)*f.w+x/smartCropScoreCell, y*w+x
+			f.detail[i] = max(f.detail[i], detail[j])
+			f.skin[i] = max(f.skin[i], skin[j])
+			f.saturation[i] = max(f.saturation[i], saturation[j])
+		}
+	}
+	return f
+}
+
+// smartCropImportance returns the weight of the point (x, y) for the crop region.
+func smartCropImportance(crop image.Rectangle, x, y float64) float64 {
+	if x < float64(crop.Min.X) || x >= float64(crop.Max.X) || y < float64(crop.Min.Y) || y >= float64(crop.Max.Y) {
+		return smartCropOutsideScore
+	}
+	px := math.Abs(0.5-(x-float64(crop.Min.X))/float64(crop.Dx())) * 2
+	py := math.Abs(0.5-(y-float64(crop.Min.Y))/float64(crop.Dy())) * 2
+	// Penalize details close to the edges of the region.
+	dx := max(px-1+smartCropEdgeRadius, 0)
+	dy := max(py-1+smartCropEdgeRadius, 0)
+	d := (dx*dx + dy*dy) * smartCropEdgeWeight
+	s := 1.41 - math.Sqrt(px*px+py*py)
+	// Favor details on the rule of thirds lines.
+	thirds := func(v float64) float64 {
+		v = (math.Mod(v-1.0/3+1, 2)*0.5 - 0.5) * 16
+		return max(1-v*v, 0)
+	}
+	s += max(0, s+d+0.5) * 1.2 * (thirds(px) + thirds(py))
+	return s + d
+}
+
+// score returns the score of the crop region given in analysis pixels.
+func (f *smartCropFeatures) score(crop image.Rectangle) float64 {
+	var detail, skin, saturation float64
+	for y := range f.h {
+		for x := range f.w {
+			i := y*f.w + x
+			imp := smartCropImportance(crop, float64(x*smartCropScoreCell+smartCropScoreCell/2), float64(y*smartCropScoreCell+smartCropScoreCell/2))
+			d := f.detail[i]
+			skin += f.skin[i] * (d + smartCropSkinBias) * imp
+			detail += d * imp
+			saturation += f.saturation[i] * (d + smartCropSaturationBias) * imp
+		}
+	}
+	return (detail*smartCropDetailWeight + skin*smartCropSkinWeight + saturation*smartCropSaturationWeight) /
+		float64(crop.Dx()*crop.Dy())
+}
+
+// best returns the top left corner of the best scoring crop region of size
+// width x height in analysis pixels.
+func (f *smartCropFeatures) best(width, height int) image.Point {
+	w, h := f.width, f.height
+	var candidates []image.Point
+	for y := 0; ; y += smartCropStep {
+		for x := 0; ; x += smartCropStep {
+			candidates = append(candidates, image.Pt(min(x, max(w-width, 0)), min(y, max(h-height, 0))))
+			if x+width >= w {
+				break
+			}
+		}
+		if y+height >= h {
+			break
+		}
+	}
+
+	scores := make([]float64, len(candidates))
+	parallel(0, len(candidates), func(is <-chan int) {
+		for i := range is {
+			scores[i] = f.score(image.Rectangle{candidates[i], candidates[i].Add(image.Pt(width, height))})
+		}
+	})
+	var best int
+	for i := range scores {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	return candidates[best]
+}
@@ -0,0 +1,40 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSmartCrop(t *testing.T) {
+	// A flat gray image with a detailed, saturated subject on the right.
+	src := image.NewNRGBA(image.Rect(0, 0, 600, 200))
+	for y := range 200 {
+		for x := range 600 {
+			c := color.NRGBA{128, 128, 128, 255}
+			if x >= 480 && x < 560 && y >= 60 && y < 140 && (x/4+y/4)%2 == 0 {
+				c = color.NRGBA{220, 40, 40, 255}
+			}
+			src.SetNRGBA(x, y, c)
+		}
+	}
+
+	img, rect := SmartCrop(src, 100, 100)
+	if got := img.Bounds(); got != image.Rect(0, 0, 100, 100) {
+		t.Fatalf("want 100x100 image, got %v", got)
+	}
+	if rect.Dx() != 200 || rect.Dy() != 200 || !rect.In(src.Rect) {
+		t.Fatalf("want 200x200 region inside the image, got %v", rect)
+	}
+	if !image.Rect(480, 60, 560, 140).In(rect) {
+		t.Errorf("want region containing the subject, got %v", rect)
+	}
+
+	sub := src.SubImage(image.Rect(100, 0, 400, 200))
+	if _, rect := SmartCrop(sub, 300, 200); rect != sub.Bounds() {
+		t.Errorf("want whole image for same aspect ratio, got %v", rect)
+	}
+	if img, rect := SmartCrop(src, 0, 100); !img.Bounds().Empty() || !rect.Empty() {
+		t.Errorf("want empty result for zero width, got %v", rect)
+	}
+}
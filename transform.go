@@ -0,0 +1,164 @@
+package imgconv
+
+import (
+	"encoding"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+var (
+	_ encoding.TextUnmarshaler = new(FlipMode)
+	_ encoding.TextMarshaler   = FlipMode(0)
+)
+
+// FlipMode defines how an image is flipped.
+type FlipMode int
+
+const (
+	// FlipNone keeps the image unchanged.
+	FlipNone FlipMode = iota
+	// FlipHorizontal flips the image from left to right.
+	FlipHorizontal
+	// FlipVertical flips the image from top to bottom.
+	FlipVertical
+	// FlipTranspose flips the image along its main diagonal.
+	FlipTranspose
+	// FlipTransverse flips the image along its anti-diagonal.
+	FlipTransverse
+)
+
+var flipModes = []string{
+	"none",
+	"horizontal",
+	"vertical",
+	"transpose",
+	"transverse",
+}
+
+func (m *FlipMode) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	for index, mode := range flipModes {
+		if s == mode {
+			*m = FlipMode(index)
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported flip mode: %s", s)
+}
+
+func (m FlipMode) MarshalText() ([]byte, error) {
+	if m < 0 || int(m) >= len(flipModes) {
+		return []byte("unknown"), nil
+	}
+	return []byte(flipModes[m]), nil
+}
+
+// Flip flips the image according to the mode.
+func Flip(img image.Image, mode FlipMode) image.Image {
+	switch mode {
+	case FlipHorizontal:
+		return flipH(img)
+	case FlipVertical:
+		return flipV(img)
+	case FlipTranspose:
+		return transpose(img)
+	case FlipTransverse:
+		return transverse(img)
+	}
+	return img
+}
+
+// FlipH flips the image horizontally (from left to right).
+func FlipH(img image.Image) image.Image { return flipH(img) }
+
+// FlipV flips the image vertically (from top to bottom).
+func FlipV(img image.Image) image.Image { return flipV(img) }
+
+// Transpose flips the image horizontally and rotates 90 degrees counter-clockwise.
+func Transpose(img image.Image) image.Image { return transpose(img) }
+
+// Transverse flips the image vertically and rotates 90 degrees counter-clockwise.
+func Transverse(img image.Image) image.Image { return transverse(img) }
+
+// Rotate90 rotates the image 90 degrees counter-clockwise.
+func Rotate90(img image.Image) image.Image { return rotate90(img) }
+
+// Rotate180 rotates the image 180 degrees counter-clockwise.
+func Rotate180(img image.Image) image.Image { return rotate180(img) }
+
+// Rotate270 rotates the image 270 degrees counter-clockwise.
+func Rotate270(img image.Image) image.Image { return rotate270(img) }
+
+// Rotate rotates the image by angle degrees counter-clockwise. The result is
+// enlarged to hold the whole rotated image, and the uncovered area is filled with
+// bgColor, transparent if nil.
+func Rotate(img image.Image, angle float64, bgColor color.Color) image.Image {
+	if bgColor == nil {
+		bgColor = color.Transparent
+	}
+	return rotate(img, angle, bgColor)
+}
+
+// RotateCrop rotates the image by angle degrees counter-clockwise, and crops the
+// result to the largest axis-aligned rectangle inside the rotated image, so that
+// no background is visible.
+func RotateCrop(img image.Image, angle float64) image.Image {
+	dst := rotate(img, angle, color.Transparent)
+	if a := angle - math.Floor(angle/360)*360; math.Mod(a, 90) == 0 {
+		return dst
+	}
+	w, h := inscribedSize(img.Bounds().Dx()-1, img.Bounds().Dy()-1, angle)
+	return CropCenter(dst, w, h)
+}
+
+// inscribedSize returns the size of the largest axis-aligned rectangle inside a
+// w x h rectangle rotated by angle degrees.
+func inscribedSize(w, h int, angle float64) (int, int) {
+	if w <= 0 || h <= 0 {
+		return 0, 0
+	}
+	sin, cos := math.Sincos(math.Pi * angle / 180)
+	sin, cos = math.Abs(sin), math.Abs(cos)
+	long, short := float64(max(w, h)), float64(min(w, h))
+	var wr, hr float64
+	if short <= 2*sin*cos*long || math.Abs(sin-cos) < 1e-10 {
+		// Two corners of the rectangle touch the longer side.
+		x := short / 2
+		if w >= h {
+			wr, hr = x/sin, x/cos
+		} else {
+			wr, hr = x/cos, x/sin
+		}
+	} else {
+		cos2 := cos*cos - sin*sin
+		wr, hr = (float64(w)*cos-float64(h)*sin)/cos2, (float64(h)*cos-float64(w)*sin)/cos2
+	}
+	return max(int(wr), 1), max(int(hr), 1)
+}
+
+// RotateOption is rotate option
+type RotateOption struct {
+	// Angle is the rotation angle in degrees counter-clockwise.
+	Angle float64
+	// Background is the color of the uncovered area, transparent if nil.
+	Background color.Color
+	// Crop crops the result to the largest rectangle without uncovered area
+	// instead of filling it with Background.
+	Crop bool
+}
+
+// SetCrop sets the option for the Rotate to crop the uncovered area or not.
+func (r *RotateOption) SetCrop(crop bool) *RotateOption {
+	r.Crop = crop
+	return r
+}
+
+func (r *RotateOption) do(base image.Image) image.Image {
+	if r.Crop {
+		return RotateCrop(base, r.Angle)
+	}
+	return Rotate(base, r.Angle, r.Background)
+}
@@ -0,0 +1,79 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFlip(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i := range 6 {
+		src.SetNRGBA(i%3, i/3, color.NRGBA{uint8(i), 0, 0, 255})
+	}
+	testCase := []struct {
+		img  image.Image
+		want [][]uint8
+	}{
+		{Flip(src, FlipHorizontal), [][]uint8{{2, 1, 0}, {5, 4, 3}}},
+		{Flip(src, FlipVertical), [][]uint8{{3, 4, 5}, {0, 1, 2}}},
+		{Flip(src, FlipTranspose), [][]uint8{{0, 3}, {1, 4}, {2, 5}}},
+		{Flip(src, FlipTransverse), [][]uint8{{5, 2}, {4, 1}, {3, 0}}},
+		{Rotate90(src), [][]uint8{{2, 5}, {1, 4}, {0, 3}}},
+		{Rotate180(src), [][]uint8{{5, 4, 3}, {2, 1, 0}}},
+		{Rotate270(src), [][]uint8{{3, 0}, {4, 1}, {5, 2}}},
+		{Rotate(src, 450, nil), [][]uint8{{2, 5}, {1, 4}, {0, 3}}},
+	}
+	for i, tc := range testCase {
+		if tc.img.Bounds().Dy() != len(tc.want) || tc.img.Bounds().Dx() != len(tc.want[0]) {
+			t.Errorf("#%d: wrong size %v", i, tc.img.Bounds())
+			continue
+		}
+		for y, row := range tc.want {
+			for x, v := range row {
+				if c := tc.img.At(x, y).(color.NRGBA); c.R != v {
+					t.Errorf("#%d: want %d at (%d, %d), got %d", i, v, x, y, c.R)
+				}
+			}
+		}
+	}
+	if Flip(src, FlipNone) != image.Image(src) {
+		t.Error("want image unchanged")
+	}
+
+	var m FlipMode
+	if err := m.UnmarshalText([]byte("Vertical")); err != nil || m != FlipVertical {
+		t.Errorf("want vertical, got %v, %v", m, err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	for i := range src.Pix {
+		src.Pix[i] = 0xff
+	}
+
+	img := Rotate(src, 30, color.Black)
+	if b := img.Bounds(); b.Dx() <= 200 || b.Dy() <= 100 {
+		t.Fatalf("want enlarged image, got %v", b)
+	}
+	if c := img.At(0, 0); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("want background color, got %v", c)
+	}
+
+	for _, angle := range []float64{5, -30, 45, 100} {
+		img := RotateCrop(src, angle)
+		b := img.Bounds()
+		if b.Dx() > 200 || b.Dy() > 200 || b.Dx() < 50 || b.Dy() < 20 {
+			t.Errorf("%g: wrong size %v", angle, b)
+		}
+		for _, p := range []image.Point{b.Min, {b.Max.X - 1, b.Min.Y}, {b.Min.X, b.Max.Y - 1}, b.Max.Sub(image.Pt(1, 1))} {
+			if _, _, _, a := img.At(p.X, p.Y).RGBA(); a != 0xffff {
+				t.Errorf("%g: want opaque corner at %v, got alpha %d", angle, p, a)
+			}
+		}
+	}
+	if img := RotateCrop(src, 180); img.Bounds().Size() != src.Rect.Size() {
+		t.Errorf("want same size, got %v", img.Bounds())
+	}
+}
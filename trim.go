@@ -0,0 +1,96 @@
+package imgconv
+
+import "image"
+
+// TrimOption is trim option
+type TrimOption struct {
+	// Tolerance is the largest difference in levels (0-255) of a channel between
+	// a pixel and the border color for the pixel to be part of the border.
+	Tolerance uint8
+	// Padding is the number of border pixels kept around the content.
+	Padding int
+}
+
+func (t *TrimOption) do(base image.Image) image.Image {
+	rect := TrimRect(base, t.Tolerance)
+	if rect.Empty() {
+		return base
+	}
+	return Crop(base, rect.Inset(-t.Padding))
+}
+
+// premultiply returns the color channels of the pixel s multiplied by its alpha,
+// so that all fully transparent pixels are equal.
+func premultiply(s []uint8) [4]int {
+	a := int(s[3])
+	return [4]int{int(s[0]) * a / 255, int(s[1]) * a / 255, int(s[2]) * a / 255, a}
+}
+
+// TrimRect returns the bounding box of the content of the image, which is the
+// pixels differing from the color of the top-left pixel by more than tolerance
+// levels in any channel, alpha included. The result is empty if the whole image
+// has the border color.
+func TrimRect(img image.Image, tolerance uint8) image.Rectangle {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w == 0 || h == 0 {
+		return image.Rectangle{}
+	}
+	border := premultiply(src.Pix[0:4])
+	tol := int(tolerance)
+	differs := func(s []uint8) bool {
+		c := premultiply(s)
+		for i := range c {
+			if d := c[i] - border[i]; d > tol || -d > tol {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The leftmost and rightmost content of each row, -1 for none.
+	lefts, rights := make([]int, h), make([]int, h)
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			row := src.Pix[y*src.Stride : y*src.Stride+w*4]
+			lefts[y], rights[y] = -1, -1
+			for x := range w {
+				if differs(row[x*4 : x*4+4]) {
+					lefts[y] = x
+					break
+				}
+			}
+			if lefts[y] < 0 {
+				continue
+			}
+			for x := w - 1; x >= lefts[y]; x-- {
+				if differs(row[x*4 : x*4+4]) {
+					rights[y] = x
+					break
+				}
+			}
+		}
+	})
+
+	rect := image.Rectangle{Min: image.Pt(w, h)}
+	for y := range h {
+		if lefts[y] < 0 {
+			continue
+		}
+		rect.Min.X, rect.Max.X = min(rect.Min.X, lefts[y]), max(rect.Max.X, rights[y]+1)
+		rect.Min.Y, rect.Max.Y = min(rect.Min.Y, y), y+1
+	}
+	if rect.Empty() {
+		return image.Rectangle{}
+	}
+	return rect.Add(img.Bounds().Min)
+}
+
+// Trim removes the uniform border of the image, such as the solid white or
+// transparent margins of screenshots. The border color is the color of the
+// top-left pixel, and pixels differing from it by at most tolerance levels in
+// each channel belong to the border. The image is returned unchanged if it
+// has no content.
+func Trim(img image.Image, tolerance uint8) image.Image {
+	return (&TrimOption{Tolerance: tolerance}).do(img)
+}
@@ -0,0 +1,49 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestTrim(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 8))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	img.SetNRGBA(3, 2, color.NRGBA{0, 0, 0, 255})
+	img.SetNRGBA(6, 5, color.NRGBA{250, 250, 250, 255})
+
+	if rect := TrimRect(img, 0); rect != image.Rect(3, 2, 7, 6) {
+		t.Errorf("want (3,2)-(7,6), got %v", rect)
+	}
+	if rect := TrimRect(img, 10); rect != image.Rect(3, 2, 4, 3) {
+		t.Errorf("want (3,2)-(4,3), got %v", rect)
+	}
+	if b := Trim(img, 0).Bounds(); b != image.Rect(0, 0, 4, 4) {
+		t.Errorf("want 4x4, got %v", b)
+	}
+	if c := Trim(img, 10).At(0, 0); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("want black, got %v", c)
+	}
+
+	// Padding is limited to the image bounds.
+	if b := (&TrimOption{Padding: 2}).do(img).Bounds(); b != image.Rect(0, 0, 8, 8) {
+		t.Errorf("want 8x8, got %v", b)
+	}
+
+	// Transparent pixels of any color are the same border.
+	transparent := image.NewNRGBA(image.Rect(2, 2, 8, 8))
+	transparent.SetNRGBA(7, 2, color.NRGBA{255, 0, 0, 0})
+	transparent.SetNRGBA(4, 4, color.NRGBA{255, 0, 0, 128})
+	if rect := TrimRect(transparent, 0); rect != image.Rect(4, 4, 5, 5) {
+		t.Errorf("want (4,4)-(5,5), got %v", rect)
+	}
+
+	blank := image.NewGray(image.Rect(0, 0, 4, 4))
+	if rect := TrimRect(blank, 0); !rect.Empty() {
+		t.Errorf("want empty rect, got %v", rect)
+	}
+	if b := Trim(blank, 0).Bounds(); b != blank.Bounds() {
+		t.Errorf("want unchanged image, got %v", b)
+	}
+}
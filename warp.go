@@ -0,0 +1,153 @@
+package imgconv
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+var errSingularMatrix = errors.New("matrix is not invertible")
+
+// AffineMatrix is a 2x3 affine transformation matrix in row-major order, which
+// maps the point (x, y) to (m[0]*x + m[1]*y + m[2], m[3]*x + m[4]*y + m[5]).
+type AffineMatrix [6]float64
+
+// IdentityMatrix is the affine matrix which keeps points unchanged.
+var IdentityMatrix = AffineMatrix{1, 0, 0, 0, 1, 0}
+
+// Multiply returns the matrix applying n and then m.
+func (m AffineMatrix) Multiply(n AffineMatrix) AffineMatrix {
+	return AffineMatrix{
+		m[0]*n[0] + m[1]*n[3], m[0]*n[1] + m[1]*n[4], m[0]*n[2] + m[1]*n[5] + m[2],
+		m[3]*n[0] + m[4]*n[3], m[3]*n[1] + m[4]*n[4], m[3]*n[2] + m[4]*n[5] + m[5],
+	}
+}
+
+// Invert returns the inverse matrix, and false if the matrix is not invertible.
+func (m AffineMatrix) Invert() (AffineMatrix, bool) {
+	det := m[0]*m[4] - m[1]*m[3]
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) {
+		return AffineMatrix{}, false
+	}
+	return AffineMatrix{
+		m[4] / det, -m[1] / det, (m[1]*m[5] - m[2]*m[4]) / det,
+		-m[3] / det, m[0] / det, (m[2]*m[3] - m[0]*m[5]) / det,
+	}, true
+}
+
+// Apply returns the transformed point (x, y).
+func (m AffineMatrix) Apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[1]*y + m[2], m[3]*x + m[4]*y + m[5]
+}
+
+// warp returns the width x height image whose pixel (x, y) is interpolated from
+// the point fn(x, y) of the source image, or is bgColor outside of it.
+func warp(src *image.NRGBA, width, height int, fn func(x, y float64) (float64, float64), bgColor color.Color) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if bgColor == nil {
+		bgColor = color.Transparent
+	}
+	bg := color.NRGBAModel.Convert(bgColor).(color.NRGBA)
+	parallel(0, height, func(ys <-chan int) {
+		for y := range ys {
+			for x := range width {
+				xf, yf := fn(float64(x), float64(y))
+				interpolatePoint(dst, x, y, src, xf, yf, bg)
+			}
+		}
+	})
+	return dst
+}
+
+// Affine transforms the image by the matrix, using bilinear interpolation. The
+// result is the bounding box of the transformed image, so the translation of
+// the matrix has no effect. The uncovered area is filled with bgColor,
+// transparent if nil.
+func Affine(img image.Image, m AffineMatrix, bgColor color.Color) (image.Image, error) {
+	inv, ok := m.Invert()
+	if !ok {
+		return nil, errSingularMatrix
+	}
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w == 0 || h == 0 {
+		return &image.NRGBA{}, nil
+	}
+
+	// The bounding box of the transformed image, whose pixel centers are at
+	// integer coordinates.
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [][2]float64{{-0.5, -0.5}, {float64(w) - 0.5, -0.5}, {-0.5, float64(h) - 0.5}, {float64(w) - 0.5, float64(h) - 0.5}} {
+		x, y := m.Apply(p[0], p[1])
+		minX, minY = min(minX, x), min(minY, y)
+		maxX, maxY = max(maxX, x), max(maxY, y)
+	}
+	// Tolerate rounding errors of exact results.
+	width := max(int(math.Ceil(maxX-minX-1e-9)), 1)
+	height := max(int(math.Ceil(maxY-minY-1e-9)), 1)
+	return warp(src, width, height, func(x, y float64) (float64, float64) {
+		return inv.Apply(x+minX+0.5, y+minY+0.5)
+	}, bgColor), nil
+}
+
+// Shear shears the image horizontally by angleX degrees and then vertically by
+// angleY degrees, in range (-90, 90). A positive angleX moves the bottom of the
+// image to the right, and a positive angleY moves its right side down.
+func Shear(img image.Image, angleX, angleY float64, bgColor color.Color) image.Image {
+	if math.Abs(angleX) >= 90 || math.Abs(angleY) >= 90 {
+		return clone(img)
+	}
+	tx, ty := math.Tan(angleX*math.Pi/180), math.Tan(angleY*math.Pi/180)
+	// Both shears have a determinant of 1.
+	dst, _ := Affine(img, AffineMatrix{1, 0, 0, ty, 1, 0}.Multiply(AffineMatrix{1, tx, 0, 0, 1, 0}), bgColor)
+	return dst
+}
+
+// perspectiveMatrix returns the projective transformation mapping the unit
+// square (0, 0), (1, 0), (1, 1), (0, 1) to the quadrilateral p.
+func perspectiveMatrix(p [4][2]float64) [8]float64 {
+	dx1, dx2, dx3 := p[1][0]-p[2][0], p[3][0]-p[2][0], p[0][0]-p[1][0]+p[2][0]-p[3][0]
+	dy1, dy2, dy3 := p[1][1]-p[2][1], p[3][1]-p[2][1], p[0][1]-p[1][1]+p[2][1]-p[3][1]
+	var g, h float64
+	if det := dx1*dy2 - dx2*dy1; det != 0 && (dx3 != 0 || dy3 != 0) {
+		g = (dx3*dy2 - dx2*dy3) / det
+		h = (dx1*dy3 - dx3*dy1) / det
+	}
+	return [8]float64{
+		p[1][0] - p[0][0] + g*p[1][0], p[3][0] - p[0][0] + h*p[3][0], p[0][0],
+		p[1][1] - p[0][1] + g*p[1][1], p[3][1] - p[0][1] + h*p[3][1], p[0][1],
+		g, h,
+	}
+}
+
+// Perspective warps the quadrilateral of the image with the corners top-left,
+// top-right, bottom-right and bottom-left to a width x height rectangle, which
+// corrects the keystone distortion of photographed documents and whiteboards.
+// If width or height is 0, it is the longer of the two corresponding sides of
+// the quadrilateral. Points outside of the image are filled with bgColor,
+// transparent if nil.
+func Perspective(img image.Image, corners [4]image.Point, width, height int, bgColor color.Color) image.Image {
+	src := toNRGBA(img)
+	origin := img.Bounds().Min
+	var p [4][2]float64
+	for i, c := range corners {
+		p[i] = [2]float64{float64(c.X - origin.X), float64(c.Y - origin.Y)}
+	}
+	if width <= 0 {
+		width = int(math.Round(max(math.Hypot(p[1][0]-p[0][0], p[1][1]-p[0][1]), math.Hypot(p[2][0]-p[3][0], p[2][1]-p[3][1])))) + 1
+	}
+	if height <= 0 {
+		height = int(math.Round(max(math.Hypot(p[3][0]-p[0][0], p[3][1]-p[0][1]), math.Hypot(p[2][0]-p[1][0], p[2][1]-p[1][1])))) + 1
+	}
+
+	m := perspectiveMatrix(p)
+	// The centers of the corner pixels of the result map to the corners.
+	sx, sy := 1/float64(max(width-1, 1)), 1/float64(max(height-1, 1))
+	return warp(src, width, height, func(x, y float64) (float64, float64) {
+		u, v := x*sx, y*sy
+		z := m[6]*u + m[7]*v + 1
+		return (m[0]*u + m[1]*v + m[2]) / z, (m[3]*u + m[4]*v + m[5]) / z
+	}, bgColor)
+}
@@ -0,0 +1,111 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestAffineMatrix(t *testing.T) {
+	m := AffineMatrix{2, 1, 3, 0, 1, -2}
+	inv, ok := m.Invert()
+	if !ok {
+		t.Fatal("want invertible matrix")
+	}
+	for i, v := range inv.Multiply(m) {
+		if math.Abs(v-IdentityMatrix[i]) > 1e-12 {
+			t.Fatalf("want identity, got %v", inv.Multiply(m))
+		}
+	}
+	if x, y := m.Apply(1, 2); x != 7 || y != 0 {
+		t.Errorf("want (7, 0), got (%g, %g)", x, y)
+	}
+	if _, ok := (AffineMatrix{1, 2, 0, 2, 4, 0}).Invert(); ok {
+		t.Error("want singular matrix")
+	}
+}
+
+func TestAffine(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(1, 1, 4, 3))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 10)
+	}
+
+	img, err := Affine(src, IdentityMatrix, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compare(t, src, img)
+
+	// Translation has no effect.
+	img, err = Affine(src, AffineMatrix{0, 1, 5, -1, 0, 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compare(t, Rotate90(src), img)
+
+	if img, _ := Affine(src, AffineMatrix{2, 0, 0, 0, 0.5, 0}, nil); img.Bounds() != image.Rect(0, 0, 6, 1) {
+		t.Errorf("want 6x1, got %v", img.Bounds())
+	}
+
+	if _, err := Affine(src, AffineMatrix{}, nil); err != errSingularMatrix {
+		t.Errorf("want %v, got %v", errSingularMatrix, err)
+	}
+}
+
+func TestShear(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for i := range src.Pix {
+		src.Pix[i] = 0xff
+	}
+	img := Shear(src, 45, 0, color.Black).(*image.NRGBA)
+	if b := img.Bounds(); b != image.Rect(0, 0, 6, 2) {
+		t.Fatalf("want 6x2, got %v", b)
+	}
+	if c := img.NRGBAAt(5, 0); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("want black top right corner, got %v", c)
+	}
+	if c := img.NRGBAAt(0, 1); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("want black bottom left corner, got %v", c)
+	}
+	if c := img.NRGBAAt(2, 0); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("want image, got %v", c)
+	}
+
+	img = Shear(src, 0, -45, nil).(*image.NRGBA)
+	if b := img.Bounds(); b != image.Rect(0, 0, 4, 6) {
+		t.Errorf("want 4x6, got %v", b)
+	}
+	compare(t, src, Shear(src, 90, 0, nil))
+}
+
+func TestPerspective(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 8, 6))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+	compare(t, src, Perspective(src, [4]image.Point{{0, 0}, {7, 0}, {7, 5}, {0, 5}}, 0, 0, nil))
+	compare(t, Crop(src, image.Rect(2, 1, 6, 4)), Perspective(src, [4]image.Point{{2, 1}, {5, 1}, {5, 3}, {2, 3}}, 0, 0, nil))
+
+	// A trapezoid, wider at the bottom, filled with white on black.
+	page := image.NewNRGBA(image.Rect(0, 0, 100, 80))
+	for y := range 80 {
+		for x := range 100 {
+			c := color.NRGBA{0, 0, 0, 255}
+			if y >= 10 && y <= 70 && float64(x) >= 30-float64(y-10)/3 && float64(x) <= 70+float64(y-10)/3 {
+				c = color.NRGBA{255, 255, 255, 255}
+			}
+			page.SetNRGBA(x, y, c)
+		}
+	}
+	img := Perspective(page, [4]image.Point{{31, 11}, {69, 11}, {88, 69}, {12, 69}}, 40, 30, nil).(*image.NRGBA)
+	if b := img.Bounds(); b != image.Rect(0, 0, 40, 30) {
+		t.Fatalf("want 40x30, got %v", b)
+	}
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0xff {
+			t.Fatalf("want white at %d, got %v", i/4, img.Pix[i:i+4])
+		}
+	}
+}